@@ -0,0 +1,545 @@
+package dnd5e
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"new-gamebook/game"
+)
+
+// DnD5eSystem はd20系（DnD5eを簡略化した）ルールを実装
+type DnD5eSystem struct {
+	Rand        *rand.Rand
+	FixedStats  bool     // trueなら設定ファイルの能力値をそのまま使う
+	Proficiency int      // 習熟ボーナス（攻撃ロールに加える）
+	HitDie      int      // 1レベルのヒット・ダイス（最大HPの元）
+	Unarmed     string   // 武器を持っていない時のダメージ
+	Saves       []string // 習熟しているセーヴィング・スローの能力値
+}
+
+// 能力値
+var abilities = []string{"STR", "DEX", "CON", "INT", "WIS", "CHA"}
+
+const (
+	defaultProficiency = 2
+	defaultHitDie      = 10
+	defaultDamage      = "1d8" // ダメージが定義されていない武器
+	defaultUnarmed     = "1"   // 素手のダメージ（筋力の修正値を加える）
+	defaultAC          = 10    // 鎧なしの防御値（敏捷力の修正値を加える）
+)
+
+func init() {
+	game.RegisterSystem(game.SystemInfo{
+		Name:        "dnd5e",
+		Description: "d20系（攻撃ロール・イニシアチブ・セーヴィング・スロー）",
+		New: func(string) (game.GameSystem, error) {
+			return NewDnD5eSystem(), nil
+		},
+	})
+}
+
+// NewDnD5eSystem は新しいDnD5eSystemインスタンスを生成
+func NewDnD5eSystem() *DnD5eSystem {
+	return &DnD5eSystem{
+		Rand:        rand.New(rand.NewSource(time.Now().UnixNano())),
+		Proficiency: defaultProficiency,
+		HitDie:      defaultHitDie,
+		Unarmed:     defaultUnarmed,
+	}
+}
+
+// インターフェースの実装を明示
+var (
+	_ game.GameSystem = (*DnD5eSystem)(nil)
+	_ game.DeathRules = (*DnD5eSystem)(nil)
+)
+
+// Initialize は設定を読み込み、敵と武器のダメージの書き方を検査する
+func (d *DnD5eSystem) Initialize(config *game.GameConfig) error {
+	if fixed, ok := config.Player["fixed_stats"].(bool); ok {
+		d.FixedStats = fixed
+	}
+	if prof, ok := config.Player["proficiency"].(int64); ok {
+		d.Proficiency = int(prof)
+	}
+	if hitDie, ok := config.Player["hit_die"].(int64); ok {
+		d.HitDie = int(hitDie)
+	}
+	// saves = ["DEX", "CON"]
+	if saves, ok := config.Player["saves"].([]interface{}); ok {
+		for _, save := range saves {
+			if str, ok := save.(string); ok {
+				d.Saves = append(d.Saves, strings.ToUpper(str))
+			}
+		}
+	}
+
+	for _, node := range config.Nodes {
+		for _, enemy := range node.Enemies {
+			if _, err := parseDice(enemyDamage(enemy)); err != nil {
+				return fmt.Errorf("node %s: enemy %s: %w", node.ID, enemy.Name, err)
+			}
+		}
+		if node.Type == "saving_throw" && !isAbility(node.SaveAbility) {
+			return fmt.Errorf("node %s: unknown save_ability %q", node.ID, node.SaveAbility)
+		}
+	}
+	for _, item := range config.Items {
+		if item.Damage == "" {
+			continue
+		}
+		if _, err := parseDice(item.Damage); err != nil {
+			return fmt.Errorf("item %s: %w", item.Name, err)
+		}
+	}
+	fmt.Println("DnD5e initialized successfully.")
+	return nil
+}
+
+// MakingPlayer は能力値を決め、HPと防御値を計算する
+func (d *DnD5eSystem) MakingPlayer(gs *game.GameState) error {
+	fmt.Println("キャラクターメイキング")
+	if d.FixedStats {
+		fmt.Println("設定ファイルの能力値を使用します。")
+	} else {
+		for {
+			for _, ability := range abilities {
+				gs.Player.Stats[ability] = d.rollAbility()
+			}
+			fmt.Println(formatAbilities(gs))
+			if game.AskYesNo(gs, "この能力値で冒険を始めますか？") {
+				break
+			}
+		}
+	}
+	for _, ability := range abilities {
+		if gs.Player.Stats[ability] <= 0 {
+			return fmt.Errorf("player ability %s is not set", ability)
+		}
+	}
+
+	if gs.Player.Stats["HP"] <= 0 || !d.FixedStats {
+		gs.Player.Stats["HP"] = d.HitDie + modifier(gs.Player.Stats["CON"])
+	}
+	if gs.Player.Stats["HP"] < 1 {
+		gs.Player.Stats["HP"] = 1
+	}
+	gs.Player.Stats["MXHP"] = gs.Player.Stats["HP"]
+	if gs.Player.Stats["AC"] <= 0 {
+		gs.Player.Stats["AC"] = defaultAC + modifier(gs.Player.Stats["DEX"])
+	}
+	fmt.Println(formatAbilities(gs))
+	fmt.Printf("HP: %d  AC: %d\n", gs.Player.Stats["HP"], gs.Player.Stats["AC"])
+	return nil
+}
+
+// rollAbility は4D6を振って低い1個を除いた合計を返す
+func (d *DnD5eSystem) rollAbility() int {
+	rolls := make([]int, 4)
+	for i := range rolls {
+		rolls[i] = d.Rand.Intn(6) + 1
+	}
+	sort.Ints(rolls)
+	return rolls[1] + rolls[2] + rolls[3]
+}
+
+// formatAbilities は能力値と修正値を1行にまとめる
+func formatAbilities(gs *game.GameState) string {
+	parts := make([]string, 0, len(abilities))
+	for _, ability := range abilities {
+		score := gs.Player.Stats[ability]
+		parts = append(parts, fmt.Sprintf("%s %d(%+d)", ability, score, modifier(score)))
+	}
+	return strings.Join(parts, "  ")
+}
+
+// IsDead はHPが0以下か確認
+func (d *DnD5eSystem) IsDead(p *game.Player) bool {
+	return p.Stats["HP"] <= 0
+}
+
+// HandleNode はノードタイプに応じて処理
+func (d *DnD5eSystem) HandleNode(gs *game.GameState, node game.Node) error {
+	fmt.Println("\n---")
+	fmt.Println(node.Text)
+	fmt.Println("---")
+
+	switch node.Type {
+	case "story":
+		return d.handleStoryNode(gs, node)
+	case "encounter":
+		if len(node.Enemies) == 0 {
+			return fmt.Errorf("no enemy defined for combat node")
+		}
+		return d.handleEncounterNode(gs, node)
+	case "saving_throw":
+		return d.handleSavingThrowNode(gs, node)
+	case "shop":
+		return game.HandleShopNode(gs, node)
+	case "end":
+		return nil
+	default:
+		return fmt.Errorf("unknown node type: %s", node.Type)
+	}
+}
+
+// UpdatePlayer はプレイヤーの状態を更新
+func (d *DnD5eSystem) UpdatePlayer(gs *game.GameState, action string) error {
+	switch action {
+	case "eat_meal":
+		if gs.InCombat {
+			fmt.Println("戦闘中は食事ができない！")
+			return nil
+		}
+		meals := gs.Player.ItemsByCategory(game.CategoryMeal)
+		if len(meals) == 0 {
+			fmt.Println("食料を持っていない。")
+			return nil
+		}
+		meal := meals[0]
+		if meal.Effect == "" {
+			gs.Player.ConsumeItem(meal)
+			fmt.Printf("%sを食べた。\n", meal.Name)
+			return nil
+		}
+		if err := gs.UseItem(meal); err != nil {
+			return err
+		}
+		fmt.Printf("%sを食べた。（%s）\n", meal.Name, meal.Effect)
+	}
+	return nil
+}
+
+// handleStoryNode はストーリーノードを処理
+func (d *DnD5eSystem) handleStoryNode(gs *game.GameState, node game.Node) error {
+	if err := game.OfferLoot(gs, node); err != nil {
+		return err
+	}
+
+	shown := game.ShowChoices(gs, node)
+	if len(shown) == 0 {
+		gs.CurrentNodeID = "game_over"
+		return fmt.Errorf("no choices available")
+	}
+	for {
+		input, err := gs.ReadChoice("選択してください (番号): ")
+		if err != nil {
+			return err // load や back で移動した
+		}
+		if choice, ok := game.PickChoice(gs, shown, input); ok {
+			gs.CurrentNodeID = choice.NextNodeID
+			return nil
+		}
+	}
+}
+
+// handleSavingThrowNode はセーヴィング・スローを行い、成否で次のノードを決める
+func (d *DnD5eSystem) handleSavingThrowNode(gs *game.GameState, node game.Node) error {
+	result := "save_failure"
+	if d.savingThrow(gs, node.SaveAbility, node.SaveDC) {
+		result = "save_success"
+	}
+	outcome, ok := game.SelectOutcome(gs, node, result)
+	if !ok {
+		gs.CurrentNodeID = "game_over"
+		return fmt.Errorf("no %s outcome found", result)
+	}
+	gs.CurrentNodeID = outcome.NextNodeID
+	return nil
+}
+
+// savingThrow は1D20+能力値の修正値（習熟していれば習熟ボーナスも）が難易度以上なら成功
+func (d *DnD5eSystem) savingThrow(gs *game.GameState, ability string, dc int) bool {
+	ability = strings.ToUpper(ability)
+	bonus := modifier(gs.Player.Stats[ability])
+	for _, save := range d.Saves {
+		if save == ability {
+			bonus += d.Proficiency
+		}
+	}
+	roll := d.d20()
+	total := roll + bonus
+	fmt.Printf("%sセーヴ: 1D20(%d)%+d = %d (難易度%d)\n", ability, roll, bonus, total, dc)
+	if total >= dc {
+		fmt.Println("成功！")
+		return true
+	}
+	fmt.Println("失敗……")
+	return false
+}
+
+// handleEncounterNode は戦闘ノードを処理
+// 最初にイニシアチブで手番を決め、毎ラウンド攻撃ロールと防御値を比べる
+func (d *DnD5eSystem) handleEncounterNode(gs *game.GameState, node game.Node) error {
+	node.Enemies = game.CopyEnemies(node.Enemies) // 戻ってきた時は敵も元の状態から
+	fmt.Println("\n--- エンカウント！ ---")
+	gs.InCombat = true
+	defer func() {
+		gs.InCombat = false
+		gs.Player.ExpireEffects(game.DurationCombat)
+	}()
+	simultaneous := node.CombatMode == game.CombatSimultaneous
+	if simultaneous {
+		fmt.Println("敵が一斉に襲いかかってくる！")
+	}
+	if node.NoEscape {
+		fmt.Println("この戦いからは逃げられない！")
+	}
+	playerFirst := d.rollInitiative(gs, node.Enemies)
+
+	result := "combat_won"
+	round := 0 // 戦闘全体のラウンド数
+	for {
+		living := game.LivingEnemies(node.Enemies)
+		if len(living) == 0 {
+			break // 全ての敵を倒した
+		}
+		if node.RoundLimit > 0 && round >= node.RoundLimit {
+			fmt.Printf("\n%dラウンドが経過した。\n", round)
+			result = "round_limit"
+			break
+		}
+		round++
+		gs.CombatRounds = round
+
+		// 攻撃する相手と攻撃してくる敵を決める
+		target := living[0]
+		attackers := living[:1]
+		if simultaneous {
+			target = game.ChooseTarget(gs, living)
+			attackers = living
+		}
+
+		fmt.Printf("\n[ラウンド%d]\n", round)
+		fmt.Printf("You (HP:%d AC:%d)\n", gs.Player.Stats["HP"], gs.Player.Stats["AC"])
+		for _, enemy := range attackers {
+			fmt.Printf("%s (HP:%d AC:%d)\n", enemy.Name, enemy.HP, enemy.AC)
+		}
+
+		if escape, ok := game.EvadeOutcome(node, round); ok && game.AskEvade(gs, escape) {
+			// 逃げる時は敵の機会攻撃を受ける
+			for _, enemy := range attackers {
+				d.enemyAttack(gs, enemy)
+			}
+			if gs.Player.Stats["HP"] <= 0 {
+				fmt.Println("あなたは倒れた！")
+				result = "combat_lost"
+				break
+			}
+			fmt.Println("戦いから逃げ出した！")
+			gs.CurrentNodeID = escape.NextNodeID
+			return nil
+		}
+
+		if playerFirst {
+			d.playerAttack(gs, target)
+		}
+		for _, enemy := range attackers {
+			if enemy.HP > 0 && gs.Player.Stats["HP"] > 0 {
+				d.enemyAttack(gs, enemy)
+			}
+		}
+		if !playerFirst && gs.Player.Stats["HP"] > 0 {
+			d.playerAttack(gs, target)
+		}
+
+		if target.HP <= 0 {
+			fmt.Printf("%sを倒した！\n", target.Name)
+		}
+		if gs.Player.Stats["HP"] <= 0 {
+			fmt.Println("あなたは倒れた！")
+			result = "combat_lost"
+			break
+		}
+	}
+
+	outcome, ok := game.SelectOutcome(gs, node, result)
+	if !ok {
+		gs.CurrentNodeID = "game_over"
+		if result == "combat_lost" {
+			return nil
+		}
+		return fmt.Errorf("no %s outcome found", result)
+	}
+	gs.CurrentNodeID = outcome.NextNodeID
+	return nil
+}
+
+// rollInitiative はプレイヤーと敵のイニシアチブを比べ、プレイヤーが先手なら true を返す
+func (d *DnD5eSystem) rollInitiative(gs *game.GameState, enemies []*game.Enemy) bool {
+	player := d.d20() + modifier(gs.Player.Stats["DEX"])
+	best := 0
+	for _, enemy := range enemies {
+		if roll := d.d20() + enemy.Initiative; roll > best {
+			best = roll
+		}
+	}
+	fmt.Printf("イニシアチブ: あなた %d vs 敵 %d\n", player, best)
+	if player >= best {
+		fmt.Println("あなたの先手だ！")
+		return true
+	}
+	fmt.Println("敵の先手だ！")
+	return false
+}
+
+// playerAttack はプレイヤーの攻撃ロールを行い、命中すれば武器のダメージを与える
+func (d *DnD5eSystem) playerAttack(gs *game.GameState, enemy *game.Enemy) {
+	bonus := modifier(gs.Player.Stats["STR"]) + d.Proficiency
+	damage := d.Unarmed
+	if weapon := gs.Player.Equipments.CurrentWeapon(); weapon != nil {
+		bonus += weapon.CSBonus
+		damage = weapon.Damage
+		if damage == "" {
+			damage = defaultDamage
+		}
+	}
+
+	roll := d.d20()
+	fmt.Printf("あなたの攻撃: 1D20(%d)%+d = %d vs AC%d\n", roll, bonus, roll+bonus, enemy.AC)
+	hit, critical := attackHits(roll, bonus, enemy.AC)
+	if !hit {
+		fmt.Println("外れた！")
+		return
+	}
+	dice, err := parseDice(damage)
+	if err != nil {
+		dice = dice1 // 武器の定義が正しくなければ1ダメージ
+	}
+	total := d.rollDamage(dice, critical) + modifier(gs.Player.Stats["STR"])
+	if total < 1 {
+		total = 1
+	}
+	if critical {
+		fmt.Println("クリティカル！")
+	}
+	enemy.HP -= total
+	fmt.Printf("%sに%dダメージを与えた！\n", enemy.Name, total)
+}
+
+// enemyAttack は敵の攻撃ロールを行い、命中すればプレイヤーがダメージを受ける
+func (d *DnD5eSystem) enemyAttack(gs *game.GameState, enemy *game.Enemy) {
+	roll := d.d20()
+	ac := gs.Player.Stats["AC"]
+	fmt.Printf("%sの攻撃: 1D20(%d)%+d = %d vs AC%d\n", enemy.Name, roll, enemy.Attack, roll+enemy.Attack, ac)
+	hit, critical := attackHits(roll, enemy.Attack, ac)
+	if !hit {
+		fmt.Println("攻撃をかわした！")
+		return
+	}
+	dice, _ := parseDice(enemyDamage(enemy)) // Initialize で検査済み
+	total := d.rollDamage(dice, critical)
+	if total < 1 {
+		total = 1
+	}
+	if critical {
+		fmt.Println("クリティカル！")
+	}
+	gs.Player.Stats["HP"] -= total
+	fmt.Printf("あなたは%dダメージを受けた！\n", total)
+}
+
+// attackHits は出目が20なら必ず命中（クリティカル）、1なら必ず外れ、それ以外は防御値以上で命中
+func attackHits(roll, bonus, ac int) (hit, critical bool) {
+	switch roll {
+	case 20:
+		return true, true
+	case 1:
+		return false, false
+	}
+	return roll+bonus >= ac, false
+}
+
+// enemyDamage は敵のダメージのダイスを返す（未定義なら1D6）
+func enemyDamage(enemy *game.Enemy) string {
+	if enemy.Damage == "" {
+		return "1d6"
+	}
+	return enemy.Damage
+}
+
+// d20 は20面ダイスを1個振る
+func (d *DnD5eSystem) d20() int {
+	return d.Rand.Intn(20) + 1
+}
+
+// modifier は能力値の修正値を返す（10,11 で0、2増えるごとに+1）
+func modifier(score int) int {
+	if score >= 10 {
+		return (score - 10) / 2
+	}
+	return -((11 - score) / 2)
+}
+
+// isAbility は能力値の名前か確認
+func isAbility(name string) bool {
+	for _, ability := range abilities {
+		if strings.EqualFold(ability, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// Dice は "2d6+3" のようなダイスの指定
+type Dice struct {
+	Count int // ダイスの個数
+	Sides int // ダイスの面数（0なら固定値のみ）
+	Bonus int
+}
+
+var dice1 = Dice{Bonus: 1}
+
+// parseDice は "1d8" "2d6+3" "1d4-1" "3" の形のダイスを解析する
+func parseDice(expr string) (Dice, error) {
+	s := strings.ToLower(strings.ReplaceAll(expr, " ", ""))
+	var dice Dice
+	if i := strings.IndexAny(s, "+-"); i > 0 {
+		bonus, err := strconv.Atoi(s[i:])
+		if err != nil {
+			return dice, fmt.Errorf("invalid dice %q", expr)
+		}
+		dice.Bonus = bonus
+		s = s[:i]
+	}
+	count, sides, found := strings.Cut(s, "d")
+	if !found {
+		bonus, err := strconv.Atoi(s)
+		if err != nil {
+			return dice, fmt.Errorf("invalid dice %q", expr)
+		}
+		dice.Bonus += bonus
+		return dice, nil
+	}
+	dice.Count = 1
+	if count != "" {
+		n, err := strconv.Atoi(count)
+		if err != nil || n <= 0 {
+			return dice, fmt.Errorf("invalid dice %q", expr)
+		}
+		dice.Count = n
+	}
+	n, err := strconv.Atoi(sides)
+	if err != nil || n <= 0 {
+		return dice, fmt.Errorf("invalid dice %q", expr)
+	}
+	dice.Sides = n
+	return dice, nil
+}
+
+// rollDamage はダイスを振る。クリティカルならダイスの個数が2倍になる
+func (d *DnD5eSystem) rollDamage(dice Dice, critical bool) int {
+	count := dice.Count
+	if critical {
+		count *= 2
+	}
+	total := dice.Bonus
+	for i := 0; i < count; i++ {
+		total += d.Rand.Intn(dice.Sides) + 1
+	}
+	return total
+}
@@ -0,0 +1,48 @@
+package dnd5e
+
+import "testing"
+
+func TestParseDice(t *testing.T) {
+	tests := []struct {
+		expr    string
+		want    Dice
+		wantErr bool
+	}{
+		{"1d8", Dice{Count: 1, Sides: 8}, false},
+		{"d6", Dice{Count: 1, Sides: 6}, false},
+		{"2D6+3", Dice{Count: 2, Sides: 6, Bonus: 3}, false},
+		{"1d4-1", Dice{Count: 1, Sides: 4, Bonus: -1}, false},
+		{"1d12 + 3", Dice{Count: 1, Sides: 12, Bonus: 3}, false},
+		{"3", Dice{Bonus: 3}, false},
+		{"-2", Dice{Bonus: -2}, false},
+		{"", Dice{}, true},
+		{"0d6", Dice{}, true},
+		{"1d0", Dice{}, true},
+		{"1d", Dice{}, true},
+		{"xd6", Dice{}, true},
+		{"1d6+x", Dice{}, true},
+	}
+	for _, tt := range tests {
+		got, err := parseDice(tt.expr)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("parseDice(%q) = %+v, want error", tt.expr, got)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("parseDice(%q) = %+v, %v, want %+v", tt.expr, got, err, tt.want)
+		}
+	}
+}
+
+func TestModifier(t *testing.T) {
+	tests := []struct{ score, want int }{
+		{1, -5}, {3, -4}, {7, -2}, {8, -1}, {9, -1}, {10, 0}, {11, 0}, {12, 1}, {15, 2}, {20, 5}, {30, 10},
+	}
+	for _, tt := range tests {
+		if got := modifier(tt.score); got != tt.want {
+			t.Errorf("modifier(%d) = %d, want %d", tt.score, got, tt.want)
+		}
+	}
+}
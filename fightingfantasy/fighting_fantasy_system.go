@@ -12,7 +12,34 @@ import (
 
 // FightingFantasySystem はFighting Fantasyゲームブックのルールを実装
 type FightingFantasySystem struct {
-	Rand *rand.Rand
+	Rand       *rand.Rand
+	FixedStats bool   // trueなら設定ファイルの能力値をそのまま使う
+	Potion     string // 設定ファイルで指定された初期ポーション（空なら選択）
+}
+
+// 初期ポーションの選択肢
+var startingPotions = []struct {
+	Name   string
+	Effect string
+}{
+	{"Potion of Skill", "restore SKILL"},
+	{"Potion of Strength", "restore STAMINA"},
+	{"Potion of Fortune", "max LUCK+1; restore LUCK"}, // 幸運のポーションは最大値も1上げる
+}
+
+const (
+	startingProvisions = 10 // 冒険開始時の食料の数
+	potionDoses        = 2  // ポーション1本で飲める回数
+)
+
+func init() {
+	game.RegisterSystem(game.SystemInfo{
+		Name:        "fightingfantasy",
+		Description: "Fighting Fantasy（技術点・体力点・運点と2D6）",
+		New: func(string) (game.GameSystem, error) {
+			return NewFightingFantasySystem(), nil
+		},
+	})
 }
 
 // NewFightingFantasySystem は新しいFightingFantasySystemインスタンスを生成
@@ -22,12 +49,29 @@ func NewFightingFantasySystem() *FightingFantasySystem {
 	}
 }
 
+// インターフェースの実装を明示
+var (
+	_ game.GameSystem = (*FightingFantasySystem)(nil)
+	_ game.DeathRules = (*FightingFantasySystem)(nil)
+)
+
 // Initialize はFightingFantasySystemを初期化
 func (ff *FightingFantasySystem) Initialize(config *game.GameConfig) error {
+	if fixed, ok := config.Player["fixed_stats"].(bool); ok {
+		ff.FixedStats = fixed
+	}
+	if potion, ok := config.Player["potion"].(string); ok {
+		ff.Potion = potion
+	}
 	fmt.Println("Fighting Fantasy initialized successfully.")
 	return nil
 }
 
+// IsDead は体力点が0以下か確認
+func (ff *FightingFantasySystem) IsDead(p *game.Player) bool {
+	return p.Stats["STAMINA"] <= 0
+}
+
 // HandleNode はノードタイプに応じて処理
 func (ff *FightingFantasySystem) HandleNode(gs *game.GameState, node game.Node) error {
 	fmt.Println("\n---")
@@ -41,6 +85,8 @@ func (ff *FightingFantasySystem) HandleNode(gs *game.GameState, node game.Node)
 		return ff.handleEncounterNode(gs, node)
 	case "luck_test":
 		return ff.handleLuckTestNode(gs, node)
+	case "shop":
+		return game.HandleShopNode(gs, node)
 	case "end":
 		return nil
 	default:
@@ -50,18 +96,131 @@ func (ff *FightingFantasySystem) HandleNode(gs *game.GameState, node game.Node)
 
 // UpdatePlayer はプレイヤーの状態を更新
 func (ff *FightingFantasySystem) UpdatePlayer(gs *game.GameState, action string) error {
-	if action == "eat_meal" && contains(gs.Player.Inventory, "Meal") {
-		gs.Player.Stats["STAMINA"] += 4
-		gs.Player.Inventory = remove(gs.Player.Inventory, "Meal")
-		fmt.Println("Ate a meal, restored 4 STAMINA!")
+	switch action {
+	case "eat_meal":
+		if gs.InCombat {
+			fmt.Println("戦闘中は食事ができない！")
+			return nil
+		}
+		provisions := gs.Player.FindItem("Provisions")
+		if provisions == nil {
+			fmt.Println("食料を持っていない。")
+			return nil
+		}
+		before := gs.Player.Stats["STAMINA"]
+		if err := gs.UseItem(provisions); err != nil {
+			return err
+		}
+		fmt.Printf("食料を食べてSTAMINAが%d回復した！（残り%d食）\n",
+			gs.Player.Stats["STAMINA"]-before, gs.Player.CountItem("Provisions"))
+	case "drink_potion":
+		if gs.InCombat {
+			fmt.Println("戦闘中はポーションを飲めない！")
+			return nil
+		}
+		potions := gs.Player.ItemsByCategory(game.CategoryPotion)
+		if len(potions) == 0 {
+			fmt.Println("ポーションを持っていない。")
+			return nil
+		}
+		potion := potions[0]
+		if err := gs.UseItem(potion); err != nil {
+			return err
+		}
+		fmt.Printf("%sを飲んだ！（SKILL:%d STAMINA:%d LUCK:%d 残り%d回）\n", potion.Name,
+			gs.Player.Stats["SKILL"], gs.Player.Stats["STAMINA"], gs.Player.Stats["LUCK"], potion.Doses)
 	}
 	return nil
 }
 
+// RegisterCommands は食料とポーションのコマンドを登録する（戦闘中は使えない）
+func (ff *FightingFantasySystem) RegisterCommands(gs *game.GameState) {
+	gs.RegisterCommand(game.Command{Name: "eat", Aliases: []string{"e"}, Help: "食料を食べる（STAMINA+4）",
+		Run: func(gs *game.GameState, _ string) error {
+			return ff.UpdatePlayer(gs, "eat_meal")
+		}})
+	gs.RegisterCommand(game.Command{Name: "drink", Aliases: []string{"p"}, Help: "ポーションを飲む",
+		Run: func(gs *game.GameState, _ string) error {
+			return ff.UpdatePlayer(gs, "drink_potion")
+		}})
+	gs.RegisterCommand(game.Command{Name: "use", Args: "<item>", Help: "アイテムを使う",
+		Run: func(gs *game.GameState, arg string) error {
+			if gs.InCombat {
+				fmt.Println("戦闘中はアイテムを使えない！")
+				return nil
+			}
+			return game.UseInventory(gs, arg)
+		}})
+}
+
+// MakingPlayer は能力値を決め、食料と初期ポーションを持たせる
 func (ff *FightingFantasySystem) MakingPlayer(gs *game.GameState) error {
+	fmt.Println("キャラクターメイキング")
+	if ff.FixedStats {
+		fmt.Println("設定ファイルの能力値を使用します。")
+	} else {
+		gs.Player.Stats["SKILL"] = ff.rollDice(1) + 6
+		gs.Player.Stats["STAMINA"] = ff.rollDice(2) + 12
+		gs.Player.Stats["LUCK"] = ff.rollDice(1) + 6
+	}
+
+	// 初期値を最大値として記録
+	for _, stat := range []string{"SKILL", "STAMINA", "LUCK"} {
+		if gs.Player.Stats[stat] <= 0 {
+			return fmt.Errorf("player stat %s is not set", stat)
+		}
+		gs.Player.Stats["MX"+stat] = gs.Player.Stats[stat]
+		fmt.Printf("%s: %d\n", stat, gs.Player.Stats[stat])
+	}
+
+	if err := gs.Player.AddItem(&game.Item{Name: "Provisions", Slot: game.SlotBackpack,
+		Category: game.CategoryMeal, Effect: "STAMINA+4", Quantity: startingProvisions}); err != nil {
+		return err
+	}
+	fmt.Printf("食料を%d食分手に入れた。\n", startingProvisions)
+
+	potion := ff.choosePotion(gs)
+	if err := gs.Player.AddItem(potion); err != nil {
+		return err
+	}
+	fmt.Printf("%sを手に入れた。\n", potion.Name)
 	return nil
 }
 
+// choosePotion は初期ポーションをプレイヤーに選ばせる
+func (ff *FightingFantasySystem) choosePotion(gs *game.GameState) *game.Item {
+	for _, p := range startingPotions {
+		if strings.EqualFold(p.Name, ff.Potion) {
+			return &game.Item{Name: p.Name, Slot: game.SlotBackpack,
+				Category: game.CategoryPotion, Effect: p.Effect, Doses: potionDoses}
+		}
+	}
+
+	fmt.Println("\n初期ポーションを選んでください:")
+	for i, p := range startingPotions {
+		fmt.Printf("%d. %s\n", i+1, p.Name)
+	}
+	for {
+		input := gs.ReadLine("選択してください (番号): ")
+		choiceNum, err := strconv.Atoi(input)
+		if err == nil && choiceNum >= 1 && choiceNum <= len(startingPotions) {
+			p := startingPotions[choiceNum-1]
+			return &game.Item{Name: p.Name, Slot: game.SlotBackpack,
+				Category: game.CategoryPotion, Effect: p.Effect, Doses: potionDoses}
+		}
+		fmt.Println("無効な入力です。もう一度入力してください。")
+	}
+}
+
+// rollDice はn個の6面ダイスを振った合計を返す
+func (ff *FightingFantasySystem) rollDice(n int) int {
+	total := 0
+	for i := 0; i < n; i++ {
+		total += ff.Rand.Intn(6) + 1
+	}
+	return total
+}
+
 // handleStoryNode はストーリーノードを処理
 func (ff *FightingFantasySystem) handleStoryNode(gs *game.GameState, node game.Node) error {
 	if len(node.Choices) == 0 {
@@ -69,130 +228,211 @@ func (ff *FightingFantasySystem) handleStoryNode(gs *game.GameState, node game.N
 		return fmt.Errorf("no choices available")
 	}
 
-	fmt.Println("\n選択肢:")
-	for i, choice := range node.Choices {
-		fmt.Printf("%d. %s\n", i+1, choice.Description)
+	if err := game.OfferLoot(gs, node); err != nil {
+		return err
 	}
 
+	shown := game.ShowChoices(gs, node)
+	if len(shown) == 0 {
+		gs.CurrentNodeID = "game_over"
+		return fmt.Errorf("no choices available")
+	}
+	fmt.Println("（e: 食料を食べる / p: ポーションを飲む）")
+
 	for {
-		fmt.Print("選択してください (番号): ")
-		input, _ := gs.Reader.ReadString('\n')
-		input = strings.TrimSpace(input)
-		choiceNum, err := strconv.Atoi(input)
-		if err != nil || choiceNum < 1 || choiceNum > len(node.Choices) {
-			//gs.display_status()
-			continue
-		}
-
-		choice := node.Choices[choiceNum-1]
-		valid := true
-		for key, condition := range choice.Conditions {
-			if key == "item" && !contains(gs.Player.Inventory, condition) {
-				valid = false
-			} else if key == "skill" && strings.HasPrefix(condition, ">") {
-				threshold, _ := strconv.Atoi(strings.TrimPrefix(condition, ">"))
-				if gs.Player.Stats["SKILL"] <= threshold {
-					valid = false
-				}
-			}
+		input, err := gs.ReadChoice("選択してください (番号): ")
+		if err != nil {
+			return err // load や back で移動した
 		}
-		if valid {
+		if choice, ok := game.PickChoice(gs, shown, input); ok {
 			gs.CurrentNodeID = choice.NextNodeID
-			break
-		} else {
-			//gs.display_status()
+			return nil
 		}
 	}
-	return nil
 }
 
 // handleEncounterNode は戦闘ノードを処理
 func (ff *FightingFantasySystem) handleEncounterNode(gs *game.GameState, node game.Node) error {
+	node.Enemies = game.CopyEnemies(node.Enemies) // 戻ってきた時は敵も元の状態から
 	fmt.Println("\n--- エンカウント！ ---")
-	for _, enemy := range node.Enemies {
-		for {
-			fmt.Printf("\nYou (STAMINA:%d SKILL:%d)\n", gs.Player.Stats["STAMINA"], gs.Player.Stats["SKILL"])
-			fmt.Printf("%s (STAMINA:%d SKILL:%d)\n", enemy.Name, enemy.HP, enemy.CS)
-
-			time.Sleep(1 * time.Second)
-			fmt.Println("\n攻撃！")
-			time.Sleep(2 * time.Second)
-
-			playerRoll := ff.Rand.Intn(6) + ff.Rand.Intn(6) + gs.Player.Stats["SKILL"]
-			enemyRoll := ff.Rand.Intn(6) + ff.Rand.Intn(6) + enemy.CS
-			fmt.Printf("あなた: %d vs 敵: %d\n", playerRoll, enemyRoll)
-
-			if playerRoll > enemyRoll {
-				enemy.HP -= 2
-				fmt.Printf("あなたは%sに2ダメージを与えた！\n", enemy.Name)
-			} else if playerRoll < enemyRoll {
-				gs.Player.Stats["STAMINA"] -= 2
-				fmt.Println("あなたは2ダメージを受けた！")
-			} else {
-				fmt.Println("引き分け！")
-			}
+	gs.InCombat = true
+	defer func() {
+		gs.InCombat = false
+		gs.Player.ExpireEffects(game.DurationCombat)
+	}()
+	simultaneous := node.CombatMode == game.CombatSimultaneous
+	if simultaneous {
+		fmt.Println("敵が一斉に襲いかかってくる！")
+	}
+	if node.NoEscape {
+		fmt.Println("この戦いからは逃げられない！")
+	}
 
-			if enemy.HP <= 0 {
-				fmt.Printf("%sを倒した！\n", enemy.Name)
+	result := "combat_won"
+	round := 0 // 戦闘全体のラウンド数
+	var enemy *game.Enemy
+	for {
+		living := game.LivingEnemies(node.Enemies)
+		if len(living) == 0 {
+			break // 全ての敵を倒した
+		}
+		if node.RoundLimit > 0 && round >= node.RoundLimit {
+			fmt.Printf("\n%dラウンドが経過した。\n", round)
+			result = "round_limit"
+			break
+		}
+		round++
+		gs.CombatRounds = round
+
+		// 攻撃する相手を決める
+		if simultaneous {
+			enemy = game.ChooseTarget(gs, living)
+		} else {
+			enemy = living[0]
+		}
+
+		fmt.Printf("\n[ラウンド%d]\n", round)
+		fmt.Printf("You (STAMINA:%d SKILL:%d)\n", gs.Player.Stats["STAMINA"], gs.Player.Stats["SKILL"])
+		fmt.Printf("%s (STAMINA:%d SKILL:%d)\n", enemy.Name, enemy.HP, enemy.CS)
+
+		if escape, ok := game.EvadeOutcome(node, round); ok && game.AskEvade(gs, escape) {
+			// 逃走すると背中に一撃を受ける（運試しで軽減できる）
+			ff.woundPlayer(gs, enemy)
+			if gs.Player.Stats["STAMINA"] <= 0 {
+				fmt.Println("あなたは倒れた！")
+				result = "combat_lost"
 				break
 			}
-			if gs.Player.Stats["STAMINA"] <= 0 {
-				gs.CurrentNodeID = "game_over"
-				return fmt.Errorf("player defeated")
+			fmt.Println("戦いから逃げ出した！")
+			gs.CurrentNodeID = escape.NextNodeID
+			return nil
+		}
+
+		time.Sleep(1 * time.Second)
+		fmt.Println("\n攻撃！")
+		time.Sleep(2 * time.Second)
+
+		playerRoll := ff.rollDice(2) + gs.Player.Stats["SKILL"]
+		enemyRoll := ff.rollDice(2) + enemy.CS
+		fmt.Printf("あなた: %d vs 敵: %d\n", playerRoll, enemyRoll)
+
+		if playerRoll > enemyRoll {
+			ff.woundEnemy(gs, enemy)
+		} else if playerRoll < enemyRoll {
+			ff.woundPlayer(gs, enemy)
+		} else {
+			fmt.Println("引き分け！")
+		}
+
+		// 同時戦闘では狙わなかった敵とも攻撃力を比べる（勝っても受け流すだけ）
+		if simultaneous {
+			for _, other := range living {
+				if other == enemy {
+					continue
+				}
+				otherRoll := ff.rollDice(2) + other.CS
+				fmt.Printf("あなた: %d vs %s: %d\n", playerRoll, other.Name, otherRoll)
+				if otherRoll > playerRoll {
+					ff.woundPlayer(gs, other)
+				} else {
+					fmt.Printf("%sの攻撃を受け流した。\n", other.Name)
+				}
 			}
 		}
+
+		if enemy.HP <= 0 {
+			fmt.Printf("%sを倒した！\n", enemy.Name)
+		}
+		if gs.Player.Stats["STAMINA"] <= 0 {
+			fmt.Println("あなたは倒れた！")
+			result = "combat_lost"
+			break
+		}
 	}
 
-	for _, outcome := range node.Outcomes {
-		if outcome.Condition == "combat_won" {
-			gs.CurrentNodeID = outcome.NextNodeID
+	outcome, ok := game.SelectOutcome(gs, node, result)
+	if !ok {
+		gs.CurrentNodeID = "game_over"
+		if result == "combat_lost" {
 			return nil
 		}
+		return fmt.Errorf("no %s outcome found", result)
 	}
-	gs.CurrentNodeID = "game_over"
-	return fmt.Errorf("no combat_won outcome found")
+	gs.CurrentNodeID = outcome.NextNodeID
+	return nil
 }
 
-// handleLuckTestNode はLuckテストノードを処理
-func (ff *FightingFantasySystem) handleLuckTestNode(gs *game.GameState, node game.Node) error {
-	roll := ff.Rand.Intn(6) + ff.Rand.Intn(6)
-	fmt.Printf("Luckテスト: 2D6 = %d (LUCK以下で成功: %d)\n", roll, gs.Player.Stats["LUCK"])
-
-	gs.Player.Stats["LUCK"] -= 1 // LuckテストごとにLUCKを1減らす
-	if gs.Player.Stats["LUCK"] < 0 {
-		gs.Player.Stats["LUCK"] = 0
+// woundEnemy は敵に傷を与える（運試しでダメージが4か1になる）
+func (ff *FightingFantasySystem) woundEnemy(gs *game.GameState, enemy *game.Enemy) {
+	damage := 2
+	if enemy.HP > damage && ff.askTestLuck(gs) {
+		if ff.testLuck(gs) {
+			damage = 4
+			fmt.Println("幸運！深手を負わせた！")
+		} else {
+			damage = 1
+			fmt.Println("不運！かすり傷に終わった。")
+		}
 	}
+	enemy.HP -= damage
+	fmt.Printf("あなたは%sに%dダメージを与えた！\n", enemy.Name, damage)
+}
 
-	for _, outcome := range node.Outcomes {
-		if outcome.Condition == "luck_success" && roll <= gs.Player.Stats["LUCK"] {
-			gs.CurrentNodeID = outcome.NextNodeID
-			return nil
-		} else if outcome.Condition == "luck_failure" && roll > gs.Player.Stats["LUCK"] {
-			gs.CurrentNodeID = outcome.NextNodeID
-			return nil
+// woundPlayer は敵の攻撃でプレイヤーが傷を負う（運試しでダメージが1か3になる）
+func (ff *FightingFantasySystem) woundPlayer(gs *game.GameState, enemy *game.Enemy) {
+	damage := 2
+	fmt.Printf("%sの攻撃が当たった！\n", enemy.Name)
+	if ff.askTestLuck(gs) {
+		if ff.testLuck(gs) {
+			damage = 1
+			fmt.Println("幸運！傷は浅かった。")
+		} else {
+			damage = 3
+			fmt.Println("不運！深手を負った！")
 		}
 	}
-	gs.CurrentNodeID = "game_over"
-	return fmt.Errorf("no valid outcome found")
+	gs.Player.Stats["STAMINA"] -= damage
+	fmt.Printf("あなたは%dダメージを受けた！\n", damage)
 }
 
-// contains はスライスに指定された文字列が含まれるか確認
-func contains(slice []string, str string) bool {
-	for _, s := range slice {
-		if s == str {
+// askTestLuck は運試しをするかプレイヤーに尋ねる
+func (ff *FightingFantasySystem) askTestLuck(gs *game.GameState) bool {
+	for {
+		input := strings.ToUpper(gs.ReadLine(fmt.Sprintf("運試しをしますか？(LUCK:%d) (Y/N)\n", gs.Player.Stats["LUCK"])))
+
+		if input == "Y" {
 			return true
+		} else if input == "N" {
+			return false
+		} else {
+			fmt.Println("Y または N を入力してください。")
 		}
 	}
-	return false
 }
 
-// remove はスライスから指定された文字列を削除
-func remove(slice []string, str string) []string {
-	result := []string{}
-	for _, s := range slice {
-		if s != str {
-			result = append(result, s)
-		}
+// testLuck は運試しを行い成否を返す（成否に関わらずLUCKを1減らす）
+func (ff *FightingFantasySystem) testLuck(gs *game.GameState) bool {
+	roll := ff.rollDice(2)
+	fmt.Printf("Luckテスト: 2D6 = %d (LUCK以下で成功: %d)\n", roll, gs.Player.Stats["LUCK"])
+	lucky := roll <= gs.Player.Stats["LUCK"]
+
+	gs.Player.Stats["LUCK"] -= 1 // LuckテストごとにLUCKを1減らす
+	if gs.Player.Stats["LUCK"] < 0 {
+		gs.Player.Stats["LUCK"] = 0
 	}
-	return result
+	return lucky
+}
+
+// handleLuckTestNode はLuckテストノードを処理
+func (ff *FightingFantasySystem) handleLuckTestNode(gs *game.GameState, node game.Node) error {
+	condition := "luck_failure"
+	if ff.testLuck(gs) {
+		condition = "luck_success"
+	}
+	if outcome, ok := game.SelectOutcome(gs, node, condition); ok {
+		gs.CurrentNodeID = outcome.NextNodeID
+		return nil
+	}
+	gs.CurrentNodeID = "game_over"
+	return fmt.Errorf("no valid outcome found")
 }
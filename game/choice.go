@@ -0,0 +1,54 @@
+package game
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// 条件を満たしていない選択肢の扱い（GameConfig.LockedChoices）
+const (
+	LockedChoicesShow = "show" // 理由を付けて表示する（既定）
+	LockedChoicesHide = "hide" // 表示しない
+)
+
+// ShowChoices は選択肢を番号付きで表示し、表示した順の選択肢を返す
+// 条件を満たしていない選択肢は理由を付けて表示するか、本の設定に従って隠す
+func ShowChoices(gs *GameState, node Node) []Choice {
+	var shown []Choice
+	for _, choice := range node.Choices {
+		if gs.LockedChoices == LockedChoicesHide && !Allowed(choice.Expr, gs) {
+			continue
+		}
+		shown = append(shown, choice)
+	}
+	if len(shown) == 0 {
+		return nil
+	}
+
+	fmt.Println("\n選択肢:")
+	for i, choice := range shown {
+		if reason := Requirement(choice.Expr, gs); reason != "" {
+			fmt.Printf("%d. × %s （%s）\n", i+1, choice.Description, reason)
+		} else {
+			fmt.Printf("%d. %s\n", i+1, choice.Description)
+		}
+	}
+	return shown
+}
+
+// PickChoice は入力された番号の選択肢を返す
+// 番号が正しくないか条件を満たしていなければ、その理由を表示して false を返す
+func PickChoice(gs *GameState, shown []Choice, input string) (Choice, bool) {
+	choiceNum, err := strconv.Atoi(strings.TrimSpace(input))
+	if err != nil || choiceNum < 1 || choiceNum > len(shown) {
+		fmt.Printf("1〜%dの番号を入力してください。\n", len(shown))
+		return Choice{}, false
+	}
+	choice := shown[choiceNum-1]
+	if reason := Requirement(choice.Expr, gs); reason != "" {
+		fmt.Printf("その選択肢は選べません（%s）\n", reason)
+		return Choice{}, false
+	}
+	return choice, true
+}
@@ -0,0 +1,109 @@
+package game
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// 複数の敵との戦い方
+const (
+	CombatSequential   = "sequential"   // 1体ずつ順番に戦う
+	CombatSimultaneous = "simultaneous" // 全員が同時に攻撃してくる
+)
+
+// SelectOutcome は条件とラウンド数（gs.CombatRounds）と条件式に合う最初の結果を返す
+func SelectOutcome(gs *GameState, node Node, condition string) (Outcome, bool) {
+	rounds := gs.CombatRounds
+	for _, outcome := range node.Outcomes {
+		if outcome.Condition != condition || !Allowed(outcome.Expr, gs) {
+			continue
+		}
+		if outcome.MinRounds > 0 && rounds < outcome.MinRounds {
+			continue
+		}
+		if outcome.MaxRounds > 0 && rounds > outcome.MaxRounds {
+			continue
+		}
+		return outcome, true
+	}
+	return Outcome{}, false
+}
+
+// CopyEnemies は戦闘で HP を減らせるよう敵を複製する
+// gs.Nodes の敵をそのまま使うと、back や load で戻った時に倒した敵が倒れたままになる
+func CopyEnemies(enemies []*Enemy) []*Enemy {
+	copies := make([]*Enemy, len(enemies))
+	for i, enemy := range enemies {
+		e := *enemy
+		copies[i] = &e
+	}
+	return copies
+}
+
+// LivingEnemies はHPが残っている敵だけを返す
+func LivingEnemies(enemies []*Enemy) []*Enemy {
+	var living []*Enemy
+	for _, enemy := range enemies {
+		if enemy.HP > 0 {
+			living = append(living, enemy)
+		}
+	}
+	return living
+}
+
+// ChooseTarget は同時戦闘で攻撃する敵をプレイヤーに選ばせる
+func ChooseTarget(gs *GameState, enemies []*Enemy) *Enemy {
+	if len(enemies) == 0 {
+		return nil
+	}
+	if len(enemies) == 1 {
+		return enemies[0]
+	}
+
+	fmt.Println("\n攻撃する相手:")
+	for i, enemy := range enemies {
+		fmt.Printf("%d. %s (HP:%d CS:%d)\n", i+1, enemy.Name, enemy.HP, enemy.CS)
+	}
+	for {
+		input := gs.ReadLine("選択してください (番号): ")
+		choiceNum, err := strconv.Atoi(input)
+		if err == nil && choiceNum >= 1 && choiceNum <= len(enemies) {
+			return enemies[choiceNum-1]
+		}
+		fmt.Println("無効な入力です。もう一度入力してください。")
+	}
+}
+
+// EvadeOutcome は現在のラウンドで逃走が可能なら逃走先の結果を返す
+func EvadeOutcome(node Node, round int) (Outcome, bool) {
+	if node.NoEscape || round < node.EvadeRound {
+		return Outcome{}, false
+	}
+	for _, outcome := range node.Outcomes {
+		if outcome.Condition == "combat_evaded" {
+			return outcome, true
+		}
+	}
+	return Outcome{}, false
+}
+
+// AskEvade は戦闘を続けるか逃走するかをプレイヤーに選ばせる
+func AskEvade(gs *GameState, evade Outcome) bool {
+	fmt.Println("\n1. 戦う")
+	if evade.Description != "" {
+		fmt.Printf("2. 逃げる（%s）\n", evade.Description)
+	} else {
+		fmt.Println("2. 逃げる")
+	}
+	for {
+		input := gs.ReadLine("選択してください (番号): ")
+		switch input {
+		case "1":
+			return false
+		case "2":
+			return true
+		default:
+			fmt.Println("1 または 2 を入力してください。")
+		}
+	}
+}
@@ -0,0 +1,532 @@
+package game
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Condition は選択肢や結果の条件式
+//
+// 書き方の例:
+//
+//	discipline "Sixth Sense" AND NOT item "Golden Key"
+//	(SKILL > 8 OR gold >= 10) AND flag ORCHID
+//	item Meal >= 2
+//	flag HERMIT >= 2
+//	visited 141
+//	visited > 2        （今のセクションを3回以上訪れた）
+type Condition interface {
+	Eval(gs *GameState) bool
+	String() string
+}
+
+// ParseCondition は条件式を解析する（空なら条件なしとしてnilを返す）
+func ParseCondition(src string) (Condition, error) {
+	if strings.TrimSpace(src) == "" {
+		return nil, nil // 条件なし
+	}
+	tokens, err := tokenize(src)
+	if err != nil {
+		return nil, fmt.Errorf("condition %q: %w", src, err)
+	}
+	p := &conditionParser{tokens: tokens}
+	cond, err := p.parseOr()
+	if err == nil && p.pos < len(p.tokens) {
+		err = p.errorf("unexpected %q", p.peek().text)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("condition %q: %w", src, err)
+	}
+	return cond, nil
+}
+
+// CompileConditions は全ての選択肢と結果の条件式を読み込み時に解析する
+// required_discipline / required_item / conditions の古い書き方も条件式に変換する
+func CompileConditions(config *GameConfig) error {
+	for i := range config.Nodes {
+		node := &config.Nodes[i]
+		for j := range node.Choices {
+			choice := &node.Choices[j]
+			cond, err := ParseCondition(choiceConditionSource(*choice))
+			if err != nil {
+				return fmt.Errorf("node %s: choice %d: %w", node.ID, j+1, err)
+			}
+			choice.Expr = cond
+		}
+		for j := range node.Outcomes {
+			outcome := &node.Outcomes[j]
+			if outcome.If == "" {
+				continue
+			}
+			cond, err := ParseCondition(outcome.If)
+			if err != nil {
+				return fmt.Errorf("node %s: outcome %d: %w", node.ID, j+1, err)
+			}
+			outcome.Expr = cond
+		}
+	}
+	return nil
+}
+
+// choiceConditionSource は選択肢の全ての条件を1つの条件式にまとめる
+func choiceConditionSource(choice Choice) string {
+	var parts []string
+	if choice.If != "" {
+		parts = append(parts, "("+choice.If+")")
+	}
+	if choice.RequiredDiscipline != "" {
+		parts = append(parts, "discipline "+strconv.Quote(choice.RequiredDiscipline))
+	}
+	if choice.RequiredItem != "" {
+		parts = append(parts, "item "+strconv.Quote(choice.RequiredItem))
+	}
+	for key, value := range choice.Conditions {
+		switch {
+		case key == "item":
+			parts = append(parts, "item "+strconv.Quote(value))
+		case strings.HasPrefix(value, ">") || strings.HasPrefix(value, "<") || strings.HasPrefix(value, "="):
+			parts = append(parts, strings.ToUpper(key)+" "+value) // "skill" = ">8"
+		default:
+			parts = append(parts, key+" "+strconv.Quote(value))
+		}
+	}
+	return strings.Join(parts, " AND ")
+}
+
+// Allowed は条件がなければtrue、あれば評価結果を返す
+func Allowed(cond Condition, gs *GameState) bool {
+	return cond == nil || cond.Eval(gs)
+}
+
+// --- 字句解析 ---
+
+type tokenKind int
+
+const (
+	tokenIdent tokenKind = iota
+	tokenNumber
+	tokenString
+	tokenOp
+	tokenLParen
+	tokenRParen
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+func tokenize(src string) ([]token, error) {
+	var tokens []token
+	runes := []rune(src)
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '(':
+			tokens = append(tokens, token{tokenLParen, "(", i})
+			i++
+		case r == ')':
+			tokens = append(tokens, token{tokenRParen, ")", i})
+			i++
+		case r == '"':
+			end := i + 1
+			for end < len(runes) && runes[end] != '"' {
+				end++
+			}
+			if end >= len(runes) {
+				return nil, fmt.Errorf("unterminated string at column %d", i+1)
+			}
+			tokens = append(tokens, token{tokenString, string(runes[i+1 : end]), i})
+			i = end + 1
+		case strings.ContainsRune("<>=!&|", r):
+			end := i + 1
+			if end < len(runes) && strings.ContainsRune("=&|", runes[end]) {
+				end++
+			}
+			tokens = append(tokens, token{tokenOp, string(runes[i:end]), i})
+			i = end
+		case unicode.IsDigit(r) || (r == '-' && i+1 < len(runes) && unicode.IsDigit(runes[i+1])):
+			end := i + 1
+			for end < len(runes) && unicode.IsDigit(runes[end]) {
+				end++
+			}
+			tokens = append(tokens, token{tokenNumber, string(runes[i:end]), i})
+			i = end
+		case unicode.IsLetter(r) || r == '_':
+			end := i + 1
+			for end < len(runes) && (unicode.IsLetter(runes[end]) || unicode.IsDigit(runes[end]) || runes[end] == '_') {
+				end++
+			}
+			tokens = append(tokens, token{tokenIdent, string(runes[i:end]), i})
+			i = end
+		default:
+			return nil, fmt.Errorf("unexpected character %q at column %d", r, i+1)
+		}
+	}
+	return tokens, nil
+}
+
+// --- 構文解析 ---
+
+type conditionParser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *conditionParser) peek() token {
+	if p.pos >= len(p.tokens) {
+		return token{kind: -1}
+	}
+	return p.tokens[p.pos]
+}
+
+func (p *conditionParser) next() token {
+	t := p.peek()
+	p.pos++
+	return t
+}
+
+func (p *conditionParser) errorf(format string, args ...interface{}) error {
+	msg := fmt.Sprintf(format, args...)
+	if p.pos >= len(p.tokens) {
+		return fmt.Errorf("%s at end of condition", msg)
+	}
+	return fmt.Errorf("%s at column %d", msg, p.tokens[p.pos].pos+1)
+}
+
+// isKeyword は AND OR NOT とその記号を判定する
+func (p *conditionParser) isKeyword(words ...string) bool {
+	t := p.peek()
+	if t.kind != tokenIdent && t.kind != tokenOp {
+		return false
+	}
+	for _, w := range words {
+		if strings.EqualFold(t.text, w) {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *conditionParser) parseOr() (Condition, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.isKeyword("OR", "||") {
+		p.next()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = orCondition{left, right}
+	}
+	return left, nil
+}
+
+func (p *conditionParser) parseAnd() (Condition, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.isKeyword("AND", "&&") {
+		p.next()
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = andCondition{left, right}
+	}
+	return left, nil
+}
+
+func (p *conditionParser) parseUnary() (Condition, error) {
+	if p.isKeyword("NOT", "!") {
+		p.next()
+		inner, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return notCondition{inner}, nil
+	}
+	if p.peek().kind == tokenLParen {
+		p.next()
+		inner, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if p.peek().kind != tokenRParen {
+			return nil, p.errorf("expected \")\"")
+		}
+		p.next()
+		return inner, nil
+	}
+	return p.parsePredicate()
+}
+
+// parsePredicate は discipline / item / flag / gold / rounds / 能力値 の判定を解析する
+func (p *conditionParser) parsePredicate() (Condition, error) {
+	t := p.peek()
+	if t.kind != tokenIdent {
+		return nil, p.errorf("expected a condition")
+	}
+	p.next()
+
+	subject := strings.ToLower(t.text)
+	switch subject {
+	case subjectDiscipline:
+		name, err := p.parseName()
+		if err != nil {
+			return nil, err
+		}
+		return compareCondition{subject: subject, name: name, op: ">=", value: 1}, nil
+	case subjectItem, subjectFlag:
+		name, err := p.parseName()
+		if err != nil {
+			return nil, err
+		}
+		cond := compareCondition{subject: subject, name: name, op: ">=", value: 1}
+		if p.peek().kind == tokenOp && !p.isKeyword("&&", "||", "!") {
+			return p.parseComparison(cond)
+		}
+		return cond, nil
+	case subjectVisited:
+		return p.parseVisited()
+	case subjectGold, subjectRounds:
+		return p.parseComparison(compareCondition{subject: subject})
+	default:
+		return p.parseComparison(compareCondition{subject: subjectStat, name: t.text})
+	}
+}
+
+// parseVisited は "visited [セクション] [比較]" を読む。セクションを省略すると今のセクション
+func (p *conditionParser) parseVisited() (Condition, error) {
+	cond := compareCondition{subject: subjectVisited, op: ">=", value: 1}
+	switch t := p.peek(); {
+	case t.kind == tokenNumber, t.kind == tokenString:
+		cond.name = p.next().text
+	case t.kind == tokenIdent && !p.isKeyword("AND", "OR", "NOT"):
+		cond.name = p.next().text
+	}
+	if p.peek().kind == tokenOp && !p.isKeyword("&&", "||", "!") {
+		return p.parseComparison(cond)
+	}
+	return cond, nil
+}
+
+// parseName は名前（識別子か "引用符付きの文字列"）を読む
+func (p *conditionParser) parseName() (string, error) {
+	t := p.peek()
+	if t.kind != tokenIdent && t.kind != tokenString {
+		return "", p.errorf("expected a name")
+	}
+	p.next()
+	return t.text, nil
+}
+
+// parseComparison は "> 8" のような比較を読む
+func (p *conditionParser) parseComparison(cond compareCondition) (Condition, error) {
+	op := p.peek()
+	if op.kind != tokenOp || !validOps[op.text] {
+		return nil, p.errorf("expected a comparison (<, <=, >, >=, ==, !=) after %q", cond.label())
+	}
+	p.next()
+	num := p.peek()
+	if num.kind != tokenNumber {
+		return nil, p.errorf("expected a number")
+	}
+	p.next()
+	value, _ := strconv.Atoi(num.text)
+	cond.op = op.text
+	if cond.op == "=" {
+		cond.op = "=="
+	}
+	cond.value = value
+	return cond, nil
+}
+
+var validOps = map[string]bool{"<": true, "<=": true, ">": true, ">=": true, "==": true, "=": true, "!=": true}
+
+// --- 条件の評価 ---
+
+// 判定の対象
+const (
+	subjectDiscipline = "discipline"
+	subjectItem       = "item"
+	subjectFlag       = "flag"
+	subjectGold       = "gold"
+	subjectRounds     = "rounds"
+	subjectVisited    = "visited"
+	subjectStat       = "stat"
+)
+
+type andCondition struct{ left, right Condition }
+
+func (c andCondition) Eval(gs *GameState) bool { return c.left.Eval(gs) && c.right.Eval(gs) }
+func (c andCondition) String() string          { return c.left.String() + " AND " + c.right.String() }
+
+type orCondition struct{ left, right Condition }
+
+func (c orCondition) Eval(gs *GameState) bool { return c.left.Eval(gs) || c.right.Eval(gs) }
+func (c orCondition) String() string          { return "(" + c.left.String() + " OR " + c.right.String() + ")" }
+
+type notCondition struct{ inner Condition }
+
+func (c notCondition) Eval(gs *GameState) bool { return !c.inner.Eval(gs) }
+func (c notCondition) String() string          { return "NOT " + c.inner.String() }
+
+// compareCondition は対象の値を数値と比べる（技能やフラグは持っていれば1）
+type compareCondition struct {
+	subject string
+	name    string
+	op      string
+	value   int
+}
+
+func (c compareCondition) Eval(gs *GameState) bool {
+	actual := c.actual(gs)
+	switch c.op {
+	case "<":
+		return actual < c.value
+	case "<=":
+		return actual <= c.value
+	case ">":
+		return actual > c.value
+	case ">=":
+		return actual >= c.value
+	case "==":
+		return actual == c.value
+	case "!=":
+		return actual != c.value
+	}
+	return false
+}
+
+// actual は判定対象の現在の値を返す
+func (c compareCondition) actual(gs *GameState) int {
+	switch c.subject {
+	case subjectDiscipline:
+		if gs.Player.HasAttribute(c.name) {
+			return 1
+		}
+		return 0
+	case subjectFlag:
+		return gs.Flag(c.name)
+	case subjectVisited:
+		if c.name == "" {
+			return gs.VisitCount(gs.CurrentNodeID)
+		}
+		return gs.VisitCount(c.name)
+	case subjectItem:
+		return gs.Player.CountItem(c.name)
+	case subjectGold:
+		return gs.Player.Gold
+	case subjectRounds:
+		return gs.CombatRounds
+	default:
+		return gs.Player.Stats[c.name]
+	}
+}
+
+// label は判定対象を表示用に返す
+func (c compareCondition) label() string {
+	switch c.subject {
+	case subjectStat:
+		return c.name
+	case subjectGold, subjectRounds:
+		return c.subject
+	case subjectVisited:
+		if c.name == "" {
+			return c.subject
+		}
+	}
+	return c.subject + " " + strconv.Quote(c.name)
+}
+
+func (c compareCondition) String() string {
+	if (c.subject == subjectDiscipline || c.subject == subjectFlag || c.subject == subjectItem || c.subject == subjectVisited) &&
+		c.op == ">=" && c.value == 1 {
+		return c.label()
+	}
+	return fmt.Sprintf("%s %s %d", c.label(), c.op, c.value)
+}
+
+// HasAttribute は属性（カイの技能など）を持っているか確認（"Sixth Sense" と "SixthSense" を同一視）
+func (p *Player) HasAttribute(name string) bool {
+	key := strings.ReplaceAll(name, " ", "")
+	for attr, active := range p.Attributes {
+		if active && strings.ReplaceAll(attr, " ", "") == key {
+			return true
+		}
+	}
+	return false
+}
+
+// --- 満たしていない条件の説明 ---
+
+// Requirement は満たしていない条件を表示用の文にする（満たしていれば空文字列）
+func Requirement(cond Condition, gs *GameState) string {
+	if Allowed(cond, gs) {
+		return ""
+	}
+	return requirement(cond, gs)
+}
+
+// requirement は AND なら満たしていない側だけを、OR なら両方を説明する
+func requirement(cond Condition, gs *GameState) string {
+	switch c := cond.(type) {
+	case andCondition:
+		var parts []string
+		for _, side := range []Condition{c.left, c.right} {
+			if !side.Eval(gs) {
+				parts = append(parts, requirement(side, gs))
+			}
+		}
+		return strings.Join(parts, "、")
+	case orCondition:
+		return requirement(c.left, gs) + " または " + requirement(c.right, gs)
+	case notCondition:
+		if inner, ok := c.inner.(compareCondition); ok {
+			return inner.describe(true)
+		}
+		return "「" + c.inner.String() + "」でないこと"
+	case compareCondition:
+		return c.describe(false)
+	}
+	return cond.String()
+}
+
+// describe は1つの判定を「Sixth Senseが必要」のような文にする
+func (c compareCondition) describe(negated bool) string {
+	simple := c.op == ">=" && c.value == 1
+	switch {
+	case simple && (c.subject == subjectDiscipline || c.subject == subjectItem):
+		if negated {
+			return c.name + "を持っていないこと"
+		}
+		return c.name + "が必要"
+	case simple && c.subject == subjectFlag:
+		if negated {
+			return "合言葉" + c.name + "がないこと"
+		}
+		return "合言葉" + c.name + "が必要"
+	case simple && c.subject == subjectVisited && c.name != "":
+		if negated {
+			return "セクション" + c.name + "を訪れていないこと"
+		}
+		return "セクション" + c.name + "を訪れていること"
+	case c.subject == subjectGold && c.op == ">=" && !negated:
+		return fmt.Sprintf("%dゴールドが必要", c.value)
+	case c.subject == subjectItem && c.op == ">=" && !negated:
+		return fmt.Sprintf("%sが%d個必要", c.name, c.value)
+	}
+	if negated {
+		return "「" + c.String() + "」でないこと"
+	}
+	return c.String() + " であること"
+}
@@ -0,0 +1,106 @@
+package game
+
+import "testing"
+
+func TestParseCondition(t *testing.T) {
+	tests := []struct {
+		src  string
+		want string // String() の結果（空ならエラー）
+	}{
+		{"", "<nil>"},
+		{"SKILL > 8", "SKILL > 8"},
+		{"HP >= -2", "HP >= -2"},
+		{"gold=10", "gold == 10"},
+		{`discipline "Sixth Sense"`, `discipline "Sixth Sense"`},
+		{"item Meal >= 2", `item "Meal" >= 2`},
+		{"flag ORCHID", `flag "ORCHID"`},
+		{"visited", "visited"},
+		{"visited > 2", "visited > 2"},
+		{"visited 141", `visited "141"`},
+		{"visited AND flag X", `visited AND flag "X"`},
+		{"NOT item Rope", `NOT item "Rope"`},
+		{"(SKILL > 8 OR gold >= 10) AND flag ORCHID", `(SKILL > 8 OR gold >= 10) AND flag "ORCHID"`},
+		{"a > 1 && b < 2 || ! c == 3", "(a > 1 AND b < 2 OR NOT c == 3)"},
+		{"SKILL", ""},
+		{"SKILL >", ""},
+		{"item", ""},
+		{"(flag X", ""},
+		{`item "Key`, ""},
+		{"flag X flag Y", ""},
+		{"gold > 1 $", ""},
+	}
+	for _, tt := range tests {
+		cond, err := ParseCondition(tt.src)
+		if tt.want == "" {
+			if err == nil {
+				t.Errorf("ParseCondition(%q) = %v, want error", tt.src, cond)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ParseCondition(%q): %v", tt.src, err)
+			continue
+		}
+		got := "<nil>"
+		if cond != nil {
+			got = cond.String()
+		}
+		if got != tt.want {
+			t.Errorf("ParseCondition(%q) = %s, want %s", tt.src, got, tt.want)
+		}
+	}
+}
+
+func TestConditionEval(t *testing.T) {
+	gs := &GameState{Player: NewPlayer(), CurrentNodeID: "5", CombatRounds: 3}
+	gs.Player.Stats["SKILL"] = 9
+	gs.Player.Stats["HP"] = -1
+	gs.Player.Gold = 10
+	gs.Player.Attributes["SixthSense"] = true
+	gs.Player.AddItem(&Item{Name: "Meal", Quantity: 2})
+	gs.SetFlag("ORCHID")
+	gs.AddCounter("HERMIT", 2)
+	gs.Visit("141")
+	for i := 0; i < 3; i++ {
+		gs.Visit("5")
+	}
+
+	tests := []struct {
+		src  string
+		want bool
+	}{
+		{"SKILL > 8", true},
+		{"SKILL != 9", false},
+		{"HP >= -1", true},
+		{"HP > -1", false},
+		{"gold >= 10 AND gold < 11", true},
+		{`discipline "Sixth Sense"`, true},
+		{"discipline Hunting", false},
+		{"item Meal", true},
+		{"item Meal >= 3", false},
+		{"flag ORCHID", true},
+		{"flag HERMIT == 2", true},
+		{"flag UNKNOWN", false},
+		{"NOT flag UNKNOWN", true},
+		{"visited 141", true},
+		{"visited 142", false},
+		{"visited", true}, // 今のセクション
+		{"visited > 2", true},
+		{"visited == 1", false},
+		{"rounds <= 3", true},
+		{"(flag UNKNOWN OR SKILL > 8) AND NOT item Rope", true},
+	}
+	for _, tt := range tests {
+		cond, err := ParseCondition(tt.src)
+		if err != nil {
+			t.Errorf("ParseCondition(%q): %v", tt.src, err)
+			continue
+		}
+		if got := Allowed(cond, gs); got != tt.want {
+			t.Errorf("Allowed(%q) = %v, want %v", tt.src, got, tt.want)
+		}
+	}
+	if !Allowed(nil, gs) {
+		t.Error("Allowed(nil) = false, want true")
+	}
+}
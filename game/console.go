@@ -0,0 +1,231 @@
+package game
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// ErrJump は load や back でセクションが変わったことを表す
+// HandleNode はこれをそのまま返し、Run は移動先のセクションから続ける
+var ErrJump = errors.New("jumped to another section")
+
+// Command はどの入力欄からでも使えるコマンド
+type Command struct {
+	Name    string
+	Aliases []string // 短縮形 "e" など
+	Args    string   // 引数の説明 "<item>"
+	Help    string
+	Jump    bool // セクションを移動するコマンド（選択肢の入力欄でのみ使える）
+	Run     func(gs *GameState, arg string) error
+}
+
+// CommandRegistrar はシステム独自のコマンドを持つ GameSystem が実装する
+// 同じ名前のコマンドを登録すると共通のコマンドを置き換える
+type CommandRegistrar interface {
+	RegisterCommands(gs *GameState)
+}
+
+// RegisterCommand はコマンドを登録する
+func (gs *GameState) RegisterCommand(cmd Command) {
+	if gs.commands == nil {
+		gs.commands = make(map[string]Command)
+	}
+	gs.commands[cmd.Name] = cmd
+}
+
+// setupCommands は共通のコマンドとシステム独自のコマンドを登録する
+func (gs *GameState) setupCommands() {
+	for _, cmd := range defaultCommands() {
+		gs.RegisterCommand(cmd)
+	}
+	if registrar, ok := gs.System.(CommandRegistrar); ok {
+		registrar.RegisterCommands(gs)
+	}
+}
+
+// lookupCommand は名前か短縮形でコマンドを探す
+func (gs *GameState) lookupCommand(name string) (Command, bool) {
+	name = strings.ToLower(name)
+	if cmd, ok := gs.commands[name]; ok {
+		return cmd, true
+	}
+	for _, cmd := range gs.commands {
+		for _, alias := range cmd.Aliases {
+			if alias == name {
+				return cmd, true
+			}
+		}
+	}
+	return Command{}, false
+}
+
+// ReadLine はプロンプトを表示して1行読む。コマンドならそれを実行して同じプロンプトに戻る
+// セクションを移動するコマンドは使えない（戦闘中や Y/N の質問など）
+func (gs *GameState) ReadLine(prompt string) string {
+	input, _ := gs.readInput(prompt, false)
+	return input
+}
+
+// ReadChoice は選択肢の入力欄で1行読む。load や back で移動したら ErrJump を返す
+func (gs *GameState) ReadChoice(prompt string) (string, error) {
+	return gs.readInput(prompt, true)
+}
+
+func (gs *GameState) readInput(prompt string, allowJump bool) (string, error) {
+	for {
+		fmt.Print(prompt)
+		line, err := gs.Reader.ReadString('\n')
+		line = strings.TrimSpace(line)
+		if err != nil && line == "" {
+			fmt.Println("\n入力が終わりました。ゲームを終了します。")
+			os.Exit(0)
+		}
+
+		name, arg, _ := strings.Cut(line, " ")
+		cmd, ok := gs.lookupCommand(name)
+		if !ok {
+			return line, nil
+		}
+		if cmd.Jump && !allowJump {
+			fmt.Printf("%sは今は使えません。\n", cmd.Name)
+			continue
+		}
+		if err := cmd.Run(gs, strings.TrimSpace(arg)); err != nil {
+			fmt.Println("エラー:", err)
+			continue
+		}
+		if cmd.Jump {
+			return "", ErrJump
+		}
+	}
+}
+
+// defaultCommands は全てのシステムで使える共通のコマンド
+func defaultCommands() []Command {
+	return []Command{
+		{Name: "help", Aliases: []string{"?"}, Help: "コマンドの一覧を表示する", Run: showHelp},
+		{Name: "status", Help: "ステータスを表示する", Run: func(gs *GameState, _ string) error {
+			gs.DisplayStatus()
+			return nil
+		}},
+		{Name: "inv", Help: "持ち物を表示する", Run: func(gs *GameState, _ string) error {
+			gs.DisplayInventory()
+			return nil
+		}},
+		{Name: "use", Args: "<item>", Help: "アイテムを使う", Run: UseInventory},
+		{Name: "drop", Args: "<item>", Help: "アイテムや装備を捨てる", Run: func(gs *GameState, arg string) error {
+			inv, err := gs.findInventory(arg)
+			if err != nil {
+				return err
+			}
+			inv.Drop(gs)
+			return nil
+		}},
+		{Name: "equip", Args: "<weapon>", Help: "武器や防具を装備する", Run: func(gs *GameState, arg string) error {
+			inv, err := gs.findInventory(arg)
+			if err != nil {
+				return err
+			}
+			switch inv.(type) {
+			case Item:
+				return fmt.Errorf("%s cannot be equipped", arg)
+			case Weapon:
+				if err := gs.CanChangeWeapon(); err != nil {
+					return err
+				}
+			}
+			inv.Use(gs)
+			return nil
+		}},
+		{Name: "unequip", Help: "構えている武器をしまう", Run: func(gs *GameState, _ string) error {
+			if err := gs.CanChangeWeapon(); err != nil {
+				return err
+			}
+			return gs.UnequipWeapon()
+		}},
+		{Name: "swap", Help: "もう一方の武器に持ち替える", Run: func(gs *GameState, _ string) error {
+			if err := gs.CanChangeWeapon(); err != nil {
+				return err
+			}
+			return gs.SwapWeapon()
+		}},
+		{Name: "eat", Help: "食料を食べる", Run: func(gs *GameState, _ string) error {
+			return gs.System.UpdatePlayer(gs, "eat_meal")
+		}},
+		{Name: "save", Args: "[file]", Help: "今のセクションの始めの状態を保存する", Run: func(gs *GameState, arg string) error {
+			return gs.Save(saveFileName(arg))
+		}},
+		{Name: "load", Args: "[file]", Help: "保存した状態から再開する", Jump: true, Run: func(gs *GameState, arg string) error {
+			return gs.Load(saveFileName(arg))
+		}},
+		{Name: "back", Help: "1つ前のセクションに戻る", Jump: true, Run: func(gs *GameState, _ string) error {
+			return gs.Back()
+		}},
+		{Name: "quit", Help: "ゲームを終了する", Run: func(gs *GameState, _ string) error {
+			if AskYesNo(gs, "ゲームを終了しますか？") {
+				fmt.Println("ゲームを終了します。")
+				os.Exit(0)
+			}
+			return nil
+		}},
+	}
+}
+
+// showHelp はコマンドの一覧を名前順に表示する
+func showHelp(gs *GameState, _ string) error {
+	names := make([]string, 0, len(gs.commands))
+	for name := range gs.commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	fmt.Println("--- コマンド ---")
+	for _, name := range names {
+		cmd := gs.commands[name]
+		usage := strings.TrimSpace(cmd.Name + " " + cmd.Args)
+		if len(cmd.Aliases) > 0 {
+			usage += " (" + strings.Join(cmd.Aliases, ", ") + ")"
+		}
+		fmt.Printf("  %-18s %s\n", usage, cmd.Help)
+	}
+	return nil
+}
+
+// UseInventory は名前が一致する持ち物を使うか装備する（use コマンド）
+func UseInventory(gs *GameState, name string) error {
+	inv, err := gs.findInventory(name)
+	if err != nil {
+		return err
+	}
+	inv.Use(gs)
+	return nil
+}
+
+// findInventory は名前が一致する持ち物か装備品を探す（大文字小文字は区別しない）
+func (gs *GameState) findInventory(name string) (Inventory, error) {
+	if name == "" {
+		return nil, fmt.Errorf("specify an item name")
+	}
+	e := gs.Player.Equipments
+	for _, container := range e.Containers {
+		for _, item := range container.Items {
+			if strings.EqualFold(item.Name, name) {
+				return *item, nil
+			}
+		}
+	}
+	for _, weapon := range []*Weapon{e.Weapon1, e.Weapon2} {
+		if weapon != nil && strings.EqualFold(weapon.Name, name) {
+			return *weapon, nil
+		}
+	}
+	for _, armor := range []*Armor{e.Head, e.Body} {
+		if armor != nil && strings.EqualFold(armor.Name, name) {
+			return *armor, nil
+		}
+	}
+	return nil, fmt.Errorf("you do not have %s", name)
+}
@@ -0,0 +1,386 @@
+package game
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// 効果の種類
+const (
+	EffectStat    = "stat"    // 能力値を増減する "HP+4"
+	EffectMax     = "max"     // 最大値を増減する "max LUCK+1"
+	EffectRestore = "restore" // 最大値まで回復する "restore LUCK"
+	EffectSet     = "set"     // フラグを立てる "set ORCHID"
+	EffectClear   = "clear"   // フラグを消す "clear ORCHID"
+	EffectGold    = "gold"    // 金貨を増減する "gold+6"
+	EffectGet     = "get"     // アイテムを手に入れる "get Dagger" "get 2 Meal"
+	EffectLose    = "lose"    // アイテムを失う "lose Meal"
+	EffectEquip   = "equip"   // 武器を手に入れて構える "equip Sword"
+	EffectCounter = "counter" // カウンターを増減する "counter HERMIT+1"
+)
+
+// 効果の持続期間
+const (
+	DurationPermanent = ""         // ずっと続く
+	DurationCombat    = "combat"   // 次の戦闘が終わるまで "CS+2 for combat"
+	DurationSections  = "sections" // 指定したセクション数だけ "CS+1 for 3 sections"
+)
+
+// Effect はアイテムなどの効果を1つ表す
+type Effect struct {
+	Kind     string
+	Target   string // 能力値名、フラグ名またはアイテム名
+	Amount   int
+	Duration string
+	Sections int // DurationSections の場合の残りセクション数
+}
+
+// ActiveEffect は期限付きで有効になっている効果
+type ActiveEffect struct {
+	Source    string // 効果の元になったアイテム名など
+	Effect    Effect
+	Applied   int // 実際に増減した量（期限切れで元に戻す）
+	Remaining int // 残りセクション数
+}
+
+// ParseEffects は "HP+4; CS+2 for combat" のような効果の記述を解析する
+func ParseEffects(src string) ([]Effect, error) {
+	var effects []Effect
+	for _, clause := range strings.Split(src, ";") {
+		clause = strings.TrimSpace(clause)
+		if clause == "" {
+			continue
+		}
+		effect, err := parseEffect(clause)
+		if err != nil {
+			return nil, fmt.Errorf("effect %q: %w", clause, err)
+		}
+		effects = append(effects, effect)
+	}
+	return effects, nil
+}
+
+// parseEffect は1つの効果を解析する
+func parseEffect(clause string) (Effect, error) {
+	tokens := strings.Fields(clause)
+	var effect Effect
+
+	// 持続期間 "for combat" / "for N sections"
+	if n := len(tokens); n >= 3 && tokens[n-2] == "for" && tokens[n-1] == "combat" {
+		effect.Duration = DurationCombat
+		tokens = tokens[:n-2]
+	} else if n >= 4 && tokens[n-3] == "for" && strings.HasPrefix(tokens[n-1], "section") {
+		sections, err := strconv.Atoi(tokens[n-2])
+		if err != nil || sections <= 0 {
+			return effect, fmt.Errorf("invalid number of sections %q", tokens[n-2])
+		}
+		effect.Duration = DurationSections
+		effect.Sections = sections
+		tokens = tokens[:n-3]
+	}
+
+	switch tokens[0] {
+	case EffectRestore, EffectSet, EffectClear, EffectGet, EffectLose, EffectEquip:
+		if effect.Duration != DurationPermanent {
+			return effect, fmt.Errorf("%s cannot have a duration", tokens[0])
+		}
+		effect.Kind = tokens[0]
+		names := tokens[1:]
+		if effect.Kind == EffectGet || effect.Kind == EffectLose {
+			// 個数の指定 "get 2 Meal"
+			effect.Amount = 1
+			if len(names) > 1 {
+				if n, err := strconv.Atoi(names[0]); err == nil {
+					effect.Amount = n
+					names = names[1:]
+				}
+			}
+		}
+		if len(names) == 0 {
+			return effect, fmt.Errorf("%s needs a name", tokens[0])
+		}
+		effect.Target = strings.Join(names, " ") // アイテム名には空白を含められる
+		return effect, nil
+	case EffectMax, EffectCounter:
+		if tokens[0] == EffectCounter && effect.Duration != DurationPermanent {
+			return effect, fmt.Errorf("counter cannot have a duration")
+		}
+		effect.Kind = tokens[0]
+		tokens = tokens[1:]
+	default:
+		effect.Kind = EffectStat
+	}
+
+	expr := strings.Join(tokens, "")
+	i := strings.IndexAny(expr, "+-")
+	if i <= 0 {
+		return effect, fmt.Errorf("expected STAT+N or STAT-N")
+	}
+	amount, err := strconv.Atoi(expr[i:])
+	if err != nil {
+		return effect, fmt.Errorf("invalid amount %q", expr[i:])
+	}
+	effect.Target = expr[:i]
+	effect.Amount = amount
+	if strings.EqualFold(effect.Target, EffectGold) {
+		if effect.Kind == EffectMax || effect.Duration != DurationPermanent {
+			return effect, fmt.Errorf("gold cannot have a maximum or duration")
+		}
+		effect.Kind = EffectGold
+		effect.Target = ""
+	}
+	return effect, nil
+}
+
+// String は効果を記述形式に戻す
+func (e Effect) String() string {
+	var s string
+	switch e.Kind {
+	case EffectStat:
+		s = fmt.Sprintf("%s%+d", e.Target, e.Amount)
+	case EffectMax, EffectCounter:
+		s = fmt.Sprintf("%s %s%+d", e.Kind, e.Target, e.Amount)
+	case EffectGold:
+		s = fmt.Sprintf("gold%+d", e.Amount)
+	case EffectGet, EffectLose:
+		s = e.Kind + " " + e.Target
+		if e.Amount > 1 {
+			s = fmt.Sprintf("%s %d %s", e.Kind, e.Amount, e.Target)
+		}
+	default:
+		s = e.Kind + " " + e.Target
+	}
+	switch e.Duration {
+	case DurationCombat:
+		s += " for combat"
+	case DurationSections:
+		s += fmt.Sprintf(" for %d sections", e.Sections)
+	}
+	return s
+}
+
+// ApplyEffects は効果をプレイヤーに適用する。期限付きの効果は記録して後で元に戻す
+func (p *Player) ApplyEffects(source string, effects []Effect) {
+	for _, effect := range effects {
+		applied := p.applyEffect(effect)
+		if effect.Duration != DurationPermanent {
+			p.ActiveEffects = append(p.ActiveEffects, ActiveEffect{
+				Source:    source,
+				Effect:    effect,
+				Applied:   applied,
+				Remaining: effect.Sections,
+			})
+		}
+	}
+}
+
+// applyEffect は効果を1つ適用し、実際に増減した量を返す
+func (p *Player) applyEffect(effect Effect) int {
+	switch effect.Kind {
+	case EffectStat:
+		before := p.Stats[effect.Target]
+		p.Stats[effect.Target] += effect.Amount
+		// 一時的でない回復は最大値を超えない
+		max, ok := p.Stats["MX"+effect.Target]
+		if effect.Duration == DurationPermanent && ok && max > 0 && p.Stats[effect.Target] > max {
+			p.Stats[effect.Target] = max
+		}
+		return p.Stats[effect.Target] - before
+	case EffectMax:
+		p.Stats["MX"+effect.Target] += effect.Amount
+		return effect.Amount
+	case EffectRestore:
+		if max, ok := p.Stats["MX"+effect.Target]; ok && p.Stats[effect.Target] < max {
+			p.Stats[effect.Target] = max
+		}
+	case EffectGold:
+		return p.AddGold(effect.Amount)
+	case EffectLose:
+		return p.RemoveItem(effect.Target, effect.Amount)
+	}
+	return 0
+}
+
+// ExpireEffects は期限が切れた効果を元に戻す
+// DurationCombat なら戦闘終了時、DurationSections ならセクションを移るたびに呼ぶ
+func (p *Player) ExpireEffects(duration string) {
+	active := p.ActiveEffects[:0]
+	for _, a := range p.ActiveEffects {
+		if a.Effect.Duration == duration {
+			a.Remaining--
+			if duration == DurationCombat || a.Remaining <= 0 {
+				p.revertEffect(a)
+				continue
+			}
+		}
+		active = append(active, a)
+	}
+	p.ActiveEffects = active
+}
+
+// revertEffect は期限付きの効果を元に戻す
+func (p *Player) revertEffect(a ActiveEffect) {
+	switch a.Effect.Kind {
+	case EffectStat:
+		p.Stats[a.Effect.Target] -= a.Applied
+	case EffectMax:
+		p.Stats["MX"+a.Effect.Target] -= a.Applied
+	}
+	fmt.Printf("%sの効果(%s)が切れた\n", a.Source, a.Effect)
+}
+
+// UseItem はアイテムの効果を適用し、消耗品なら1回分消費する
+// フラグを操作する効果（"set ORCHID" など）はゲームの状態に適用する
+func (gs *GameState) UseItem(item *Item) error {
+	effects, err := ParseEffects(item.Effect)
+	if err != nil {
+		return err
+	}
+	if len(effects) == 0 {
+		return fmt.Errorf("%s has no effect", item.Name)
+	}
+	applied := 0
+	for _, effect := range effects {
+		switch {
+		case isFlagEffect(effect):
+			gs.applyFlagEffect(effect)
+		case effect.Kind == EffectGet:
+			if gs.acquireItems(effect.Target, effect.Amount) == 0 {
+				continue
+			}
+		case effect.Kind == EffectEquip:
+			if !gs.equipNewWeapon(effect.Target) {
+				continue
+			}
+		default:
+			gs.Player.ApplyEffects(item.Name, []Effect{effect})
+		}
+		applied++
+	}
+	if applied == 0 {
+		return fmt.Errorf("%s had no effect", item.Name) // 何も起きなければ消費しない
+	}
+	if !item.Reusable {
+		gs.Player.ConsumeItem(item)
+	}
+	return nil
+}
+
+// ApplyNodeEffects はセクションに入った時の効果を適用し、内容を記録する
+// 金貨やアイテムを得る効果は最初に得た時だけ適用し（Taken に記録する）、
+// ダメージなどそれ以外の効果はセクションに入るたびに適用する
+func (gs *GameState) ApplyNodeEffects(node Node) error {
+	seen := make(map[string]int) // 同じ効果が複数ある場合に数える
+	for _, src := range node.Effects {
+		effects, err := ParseEffects(src)
+		if err != nil {
+			return fmt.Errorf("node %s: %w", node.ID, err)
+		}
+		for _, effect := range effects {
+			key := effect.String()
+			gain := isGainEffect(effect)
+			if gain {
+				seen[key]++
+				if gs.TakenCount(node.ID, key) >= seen[key] {
+					continue // 前に来た時に受け取った
+				}
+			}
+			// 記録するのは実際に適用した分だけ（持ちきれずに諦めた物などは記録しない）
+			switch effect.Kind {
+			case EffectGet:
+				effect.Amount = gs.acquireItems(effect.Target, effect.Amount)
+				if effect.Amount == 0 {
+					continue
+				}
+			case EffectEquip:
+				if !gs.equipNewWeapon(effect.Target) {
+					continue
+				}
+			case EffectSet, EffectClear, EffectCounter:
+				gs.applyFlagEffect(effect)
+			case EffectLose:
+				removed := gs.Player.RemoveItem(effect.Target, effect.Amount)
+				if removed == 0 && gs.dropEquipment(effect.Target) {
+					removed = 1
+				}
+				if removed == 0 {
+					continue
+				}
+				effect.Amount = removed
+			case EffectGold:
+				effect.Amount = gs.Player.AddGold(effect.Amount)
+				if effect.Amount == 0 {
+					continue
+				}
+			default:
+				gs.Player.ApplyEffects("セクション"+node.ID, []Effect{effect})
+			}
+			if gain {
+				gs.Take(node.ID, key, 1)
+			}
+			gs.Logf("セクション%s: %s", node.ID, effect)
+		}
+	}
+	return nil
+}
+
+// isGainEffect はセクションで一度だけ受け取れる効果（金貨やアイテムを得る）か確認
+func isGainEffect(effect Effect) bool {
+	switch effect.Kind {
+	case EffectGet, EffectEquip:
+		return true
+	case EffectGold:
+		return effect.Amount > 0
+	}
+	return false
+}
+
+// acquireItems はアイテムを手に入れ、実際に手に入れた数を返す
+// 武器と防具は枠に1つずつ、それ以外はまとめて入れ物に入れる
+func (gs *GameState) acquireItems(name string, quantity int) int {
+	item := gs.NewItem(name)
+	if item.Category != CategoryWeapon && item.Category != CategoryArmor {
+		item.Quantity = quantity
+		if !gs.Acquire(item) {
+			return 0
+		}
+		return quantity
+	}
+	got := 0
+	for n := 0; n < quantity; n++ {
+		if gs.Acquire(gs.NewItem(name)) {
+			got++
+		}
+	}
+	return got
+}
+
+// equipNewWeapon は武器を手に入れて構える。手に入れられなければ false
+func (gs *GameState) equipNewWeapon(name string) bool {
+	weapon := gs.NewWeapon(name)
+	weapon.Get(gs)
+	if !gs.Player.HasItem(name) {
+		return false
+	}
+	weapon.Use(gs)
+	return true
+}
+
+// dropEquipment は名前が一致する武器か防具を捨てる。捨てられたら true
+func (gs *GameState) dropEquipment(name string) bool {
+	e := gs.Player.Equipments
+	for _, weapon := range []*Weapon{e.Weapon1, e.Weapon2} {
+		if weapon != nil && weapon.Name == name {
+			weapon.Drop(gs)
+			return true
+		}
+	}
+	for _, armor := range []*Armor{e.Head, e.Body} {
+		if armor != nil && armor.Name == name {
+			armor.Drop(gs)
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,179 @@
+package game
+
+import (
+	"bufio"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseEffects(t *testing.T) {
+	tests := []struct {
+		src  string
+		want []Effect // nil ならエラー
+	}{
+		{"HP+4", []Effect{{Kind: EffectStat, Target: "HP", Amount: 4}}},
+		{"HP -2", []Effect{{Kind: EffectStat, Target: "HP", Amount: -2}}},
+		{"CS+2 for combat", []Effect{{Kind: EffectStat, Target: "CS", Amount: 2, Duration: DurationCombat}}},
+		{"CS+1 for 3 sections", []Effect{{Kind: EffectStat, Target: "CS", Amount: 1, Duration: DurationSections, Sections: 3}}},
+		{"CS-1 for 1 section", []Effect{{Kind: EffectStat, Target: "CS", Amount: -1, Duration: DurationSections, Sections: 1}}},
+		{"max LUCK+1; restore LUCK", []Effect{
+			{Kind: EffectMax, Target: "LUCK", Amount: 1},
+			{Kind: EffectRestore, Target: "LUCK"},
+		}},
+		{"gold-6", []Effect{{Kind: EffectGold, Amount: -6}}},
+		{"GOLD+3", []Effect{{Kind: EffectGold, Amount: 3}}},
+		{"set ORCHID; clear ORCHID", []Effect{
+			{Kind: EffectSet, Target: "ORCHID"},
+			{Kind: EffectClear, Target: "ORCHID"},
+		}},
+		{"counter HERMIT+1", []Effect{{Kind: EffectCounter, Target: "HERMIT", Amount: 1}}},
+		{"get 2 Meal", []Effect{{Kind: EffectGet, Target: "Meal", Amount: 2}}},
+		{"get Golden Key", []Effect{{Kind: EffectGet, Target: "Golden Key", Amount: 1}}},
+		{"get 2", []Effect{{Kind: EffectGet, Target: "2", Amount: 1}}},
+		{"lose Meal", []Effect{{Kind: EffectLose, Target: "Meal", Amount: 1}}},
+		{"equip Sword", []Effect{{Kind: EffectEquip, Target: "Sword"}}},
+		{" ; HP+1 ;", []Effect{{Kind: EffectStat, Target: "HP", Amount: 1}}},
+		{"HP", nil},
+		{"set", nil},
+		{"HP+x", nil},
+		{"+4", nil},
+		{"get", nil},
+		{"CS+1 for 0 sections", nil},
+		{"CS+1 for x sections", nil},
+		{"set ORCHID for combat", nil},
+		{"counter HERMIT+1 for combat", nil},
+		{"gold+1 for combat", nil},
+		{"max gold+1", nil},
+	}
+	for _, tt := range tests {
+		got, err := ParseEffects(tt.src)
+		if tt.want == nil {
+			if err == nil {
+				t.Errorf("ParseEffects(%q) = %v, want error", tt.src, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ParseEffects(%q): %v", tt.src, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ParseEffects(%q) = %+v, want %+v", tt.src, got, tt.want)
+		}
+	}
+	if got, err := ParseEffects(""); err != nil || got != nil {
+		t.Errorf("ParseEffects(\"\") = %v, %v, want nil, nil", got, err)
+	}
+}
+
+func TestEffectString(t *testing.T) {
+	for _, src := range []string{
+		"HP+4", "CS-2 for combat", "CS+1 for 3 sections", "max LUCK+1", "restore LUCK",
+		"gold-6", "set ORCHID", "clear ORCHID", "counter HERMIT-1", "get Meal", "get 2 Meal", "lose Golden Key", "equip Sword",
+	} {
+		effects, err := ParseEffects(src)
+		if err != nil {
+			t.Errorf("ParseEffects(%q): %v", src, err)
+			continue
+		}
+		if got := effects[0].String(); got != src {
+			t.Errorf("String() = %q, want %q", got, src)
+		}
+	}
+}
+
+func TestApplyAndExpireEffects(t *testing.T) {
+	p := NewPlayer()
+	p.Stats["HP"] = 18
+	p.Stats["MXHP"] = 20
+	p.Stats["CS"] = 15
+
+	effects, err := ParseEffects("HP+4; CS+2 for combat; CS+1 for 2 sections")
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.ApplyEffects("Potion", effects)
+	if p.Stats["HP"] != 20 {
+		t.Errorf("HP = %d, want 20 (capped at MXHP)", p.Stats["HP"])
+	}
+	if p.Stats["CS"] != 18 {
+		t.Errorf("CS = %d, want 18", p.Stats["CS"])
+	}
+
+	p.ExpireEffects(DurationCombat)
+	if p.Stats["CS"] != 16 {
+		t.Errorf("CS after combat = %d, want 16", p.Stats["CS"])
+	}
+	p.ExpireEffects(DurationSections)
+	if p.Stats["CS"] != 16 {
+		t.Errorf("CS after 1 section = %d, want 16", p.Stats["CS"])
+	}
+	p.ExpireEffects(DurationSections)
+	if p.Stats["CS"] != 15 || len(p.ActiveEffects) != 0 {
+		t.Errorf("CS after 2 sections = %d with %d active, want 15 with 0", p.Stats["CS"], len(p.ActiveEffects))
+	}
+}
+
+func TestApplyNodeEffectsRevisit(t *testing.T) {
+	gs := &GameState{Player: NewPlayer()}
+	gs.Player.Stats["HP"] = 10
+	node := Node{ID: "33", Effects: []string{"gold+3; get Meal; get Meal; HP-1; gold-1"}}
+
+	for visit := 1; visit <= 2; visit++ {
+		if err := gs.ApplyNodeEffects(node); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// 金貨とアイテムは1回目だけ、ダメージと支払いは毎回
+	if gs.Player.Gold != 1 {
+		t.Errorf("Gold = %d, want 1", gs.Player.Gold)
+	}
+	if got := gs.Player.CountItem("Meal"); got != 2 {
+		t.Errorf("Meal = %d, want 2", got)
+	}
+	if gs.Player.Stats["HP"] != 8 {
+		t.Errorf("HP = %d, want 8", gs.Player.Stats["HP"])
+	}
+}
+
+func TestUseItemGetAndEquip(t *testing.T) {
+	gs := &GameState{
+		Player: NewPlayer(),
+		Reader: bufio.NewReader(strings.NewReader("\n")), // 満杯の時は何も捨てない
+		Items: map[string]Item{
+			"Sword": {Name: "Sword", Category: CategoryWeapon},
+		},
+	}
+	bag := &Item{Name: "Bag", Effect: "get 2 Meal"}
+	scroll := &Item{Name: "Scroll", Effect: "equip Sword"}
+	gs.Player.AddItem(bag)
+	gs.Player.AddItem(scroll)
+
+	if err := gs.UseItem(scroll); err != nil {
+		t.Fatal(err)
+	}
+	if w := gs.Player.Equipments.CurrentWeapon(); w == nil || w.Name != "Sword" {
+		t.Errorf("CurrentWeapon = %v, want Sword", w)
+	}
+	if gs.Player.HasItem("Scroll") {
+		t.Error("Scroll was not used up")
+	}
+
+	// 持ちきれなければ効果がなく、アイテムも減らない
+	gs.Player.Container(SlotBackpack).Capacity = 2
+	if err := gs.UseItem(bag); err == nil {
+		t.Error("UseItem with a full backpack succeeded")
+	}
+	if !gs.Player.HasItem("Bag") || gs.Player.HasItem("Meal") {
+		t.Error("Bag was used up without giving anything")
+	}
+
+	gs.Player.Container(SlotBackpack).Capacity = 0
+	if err := gs.UseItem(bag); err != nil {
+		t.Fatal(err)
+	}
+	if got := gs.Player.CountItem("Meal"); got != 2 || gs.Player.HasItem("Bag") {
+		t.Errorf("Meal = %d, Bag left = %v, want 2 and false", got, gs.Player.HasItem("Bag"))
+	}
+}
@@ -0,0 +1,77 @@
+package game
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Flag はフラグやカウンターの値を返す（未設定なら0）
+func (gs *GameState) Flag(name string) int {
+	return gs.Flags[name]
+}
+
+// SetFlag はフラグを立てる（合言葉を覚えた、など）
+func (gs *GameState) SetFlag(name string) {
+	if gs.Flags == nil {
+		gs.Flags = make(map[string]int)
+	}
+	if gs.Flags[name] == 0 {
+		gs.Flags[name] = 1
+	}
+}
+
+// ClearFlag はフラグやカウンターを消す
+func (gs *GameState) ClearFlag(name string) {
+	delete(gs.Flags, name)
+}
+
+// AddCounter はカウンターを増減し、新しい値を返す（0以下になったら消す）
+func (gs *GameState) AddCounter(name string, amount int) int {
+	if gs.Flags == nil {
+		gs.Flags = make(map[string]int)
+	}
+	gs.Flags[name] += amount
+	if gs.Flags[name] <= 0 {
+		delete(gs.Flags, name)
+		return 0
+	}
+	return gs.Flags[name]
+}
+
+// applyFlagEffect は set / clear / counter の効果を適用する
+func (gs *GameState) applyFlagEffect(effect Effect) {
+	switch effect.Kind {
+	case EffectSet:
+		gs.SetFlag(effect.Target)
+	case EffectClear:
+		gs.ClearFlag(effect.Target)
+	case EffectCounter:
+		gs.AddCounter(effect.Target, effect.Amount)
+	}
+}
+
+// isFlagEffect はフラグを操作する効果か確認
+func isFlagEffect(effect Effect) bool {
+	return effect.Kind == EffectSet || effect.Kind == EffectClear || effect.Kind == EffectCounter
+}
+
+// displayFlags はフラグとカウンターを名前順に表示する
+func (gs *GameState) displayFlags() {
+	fmt.Println("合言葉・フラグ:")
+	if len(gs.Flags) == 0 {
+		fmt.Println("  ありません。")
+		return
+	}
+	names := make([]string, 0, len(gs.Flags))
+	for name := range gs.Flags {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if value := gs.Flags[name]; value == 1 {
+			fmt.Printf("  - %s\n", name)
+		} else {
+			fmt.Printf("  - %s: %d\n", name, value)
+		}
+	}
+}
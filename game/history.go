@@ -0,0 +1,62 @@
+package game
+
+import (
+	"sort"
+	"strconv"
+)
+
+// Visit はセクションに入ったことを記録し、通算の訪問回数を返す
+func (gs *GameState) Visit(id string) int {
+	if gs.Visits == nil {
+		gs.Visits = make(map[string]int)
+	}
+	gs.Visits[id]++
+	return gs.Visits[id]
+}
+
+// VisitCount はセクションを訪れた回数を返す（未訪問なら0）
+func (gs *GameState) VisitCount(id string) int {
+	return gs.Visits[id]
+}
+
+// VisitedSections は訪れたセクションのIDを番号順に返す
+func (gs *GameState) VisitedSections() []string {
+	ids := make([]string, 0, len(gs.Visits))
+	for id := range gs.Visits {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		a, errA := strconv.Atoi(ids[i])
+		b, errB := strconv.Atoi(ids[j])
+		if errA == nil && errB == nil {
+			return a < b
+		}
+		if (errA == nil) != (errB == nil) {
+			return errA == nil // 番号のセクションを先に並べる
+		}
+		return ids[i] < ids[j]
+	})
+	return ids
+}
+
+// takenKey は Taken のキー（セクションIDとアイテム名）
+func takenKey(nodeID, name string) string {
+	return nodeID + "/" + name
+}
+
+// TakenCount はセクションから持ち出したアイテムの数を返す
+func (gs *GameState) TakenCount(nodeID, name string) int {
+	return gs.Taken[takenKey(nodeID, name)]
+}
+
+// Take はセクションからアイテムを持ち出したことを記録する（戻した場合は負の数）
+func (gs *GameState) Take(nodeID, name string, n int) {
+	if gs.Taken == nil {
+		gs.Taken = make(map[string]int)
+	}
+	key := takenKey(nodeID, name)
+	gs.Taken[key] += n
+	if gs.Taken[key] == 0 {
+		delete(gs.Taken, key)
+	}
+}
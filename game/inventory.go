@@ -0,0 +1,338 @@
+package game
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// 入れ物の名前（Item.Slot で入れ先を指定する）
+const (
+	SlotBackpack = "Backpack"
+	SlotSpecial  = "Special" // 特別なアイテム（バックパックを失っても無くならない）
+)
+
+// アイテムの分類
+const (
+	CategoryItem    = ""        // 分類なしの一般アイテム
+	CategoryWeapon  = "weapon"  // 武器
+	CategoryArmor   = "armor"   // 防具
+	CategoryMeal    = "meal"    // 食料
+	CategoryPotion  = "potion"  // ポーション
+	CategorySpecial = "special" // 特別なアイテム
+)
+
+// ErrContainerFull は入れ物がいっぱいでアイテムを入れられないことを表す
+var ErrContainerFull = fmt.Errorf("container is full")
+
+// ContainerRules は入れ物の容量や所持金の上限を決める GameSystem が実装する
+// 設定ファイルの持ち物と所持金を入れる前に呼ぶ
+type ContainerRules interface {
+	SetupContainers(p *Player)
+}
+
+// Container はアイテムを入れる入れ物（バックパックなど）
+type Container struct {
+	Name     string
+	Capacity int // 入れられる数の上限（0なら無制限）
+	Items    []*Item
+}
+
+// NewContainer は新しい入れ物を生成
+func NewContainer(name string, capacity int) *Container {
+	return &Container{Name: name, Capacity: capacity}
+}
+
+// Used は入っているアイテムの総数を返す
+func (c *Container) Used() int {
+	used := 0
+	for _, item := range c.Items {
+		used += item.Count()
+	}
+	return used
+}
+
+// Free は空きの数を返す（無制限なら-1）
+func (c *Container) Free() int {
+	if c.Capacity <= 0 {
+		return -1
+	}
+	return c.Capacity - c.Used()
+}
+
+// Find は名前が一致するアイテムを返す
+func (c *Container) Find(name string) *Item {
+	for _, item := range c.Items {
+		if item.Name == name {
+			return item
+		}
+	}
+	return nil
+}
+
+// Count は名前が一致するアイテムの数を返す
+func (c *Container) Count(name string) int {
+	count := 0
+	for _, item := range c.Items {
+		if item.Name == name {
+			count += item.Count()
+		}
+	}
+	return count
+}
+
+// Add はアイテムを入れる。同じ名前のアイテムがあれば数をまとめる
+// 使用回数のあるアイテムは1個ずつ回数を数えるので、まとめずに1個ずつ入れる
+func (c *Container) Add(item *Item) error {
+	if c.Capacity > 0 && c.Used()+item.Count() > c.Capacity {
+		return ErrContainerFull
+	}
+	item.Slot = c.Name
+	if item.Doses > 0 {
+		for n := item.Count(); n > 1; n-- {
+			unit := *item
+			unit.Quantity = 1
+			c.Items = append(c.Items, &unit)
+		}
+		item.Quantity = 1
+		c.Items = append(c.Items, item)
+		return nil
+	}
+	if existing := c.Find(item.Name); existing != nil && existing.Doses == 0 {
+		existing.Quantity = existing.Count() + item.Count()
+		return nil
+	}
+	c.Items = append(c.Items, item)
+	return nil
+}
+
+// Remove は名前が一致するアイテムを指定数取り除き、取り除いた数を返す
+func (c *Container) Remove(name string, quantity int) int {
+	removed := 0
+	items := c.Items[:0]
+	for _, item := range c.Items {
+		if item.Name == name && removed < quantity {
+			take := item.Count()
+			if take > quantity-removed {
+				take = quantity - removed
+			}
+			removed += take
+			if take == item.Count() {
+				continue // 全部取り除いた
+			}
+			item.Quantity = item.Count() - take
+		}
+		items = append(items, item)
+	}
+	c.Items = items
+	return removed
+}
+
+// RemoveItem はアイテムを丸ごと取り除く
+func (c *Container) RemoveItem(target *Item) bool {
+	for i, item := range c.Items {
+		if item == target {
+			c.Items = append(c.Items[:i], c.Items[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// Count はアイテムの数を返す（Quantity が未設定なら1個）
+func (i *Item) Count() int {
+	if i.Quantity == 0 {
+		return 1
+	}
+	return i.Quantity
+}
+
+// NewPlayer は空のプレイヤーを生成
+func NewPlayer() *Player {
+	return &Player{
+		Stats:      make(map[string]int),
+		Attributes: make(map[string]bool),
+		Equipments: &Equipment{
+			Containers: map[string]*Container{
+				SlotBackpack: NewContainer(SlotBackpack, 0),
+			},
+		},
+	}
+}
+
+// Container は名前で入れ物を返す（空の名前はバックパック）
+func (p *Player) Container(name string) *Container {
+	if name == "" {
+		name = SlotBackpack
+	}
+	return p.Equipments.Containers[name]
+}
+
+// AddItem はアイテムを Slot で指定された入れ物に入れる
+// Slot が空なら特別なアイテムは Special、それ以外はバックパックに入れる
+func (p *Player) AddItem(item *Item) error {
+	if item.Slot == "" && item.Category == CategorySpecial && p.Container(SlotSpecial) != nil {
+		item.Slot = SlotSpecial
+	}
+	container := p.Container(item.Slot)
+	if container == nil {
+		return fmt.Errorf("unknown container: %s", item.Slot)
+	}
+	return container.Add(item)
+}
+
+// RemoveItem は全ての入れ物から名前が一致するアイテムを指定数取り除き、取り除いた数を返す
+func (p *Player) RemoveItem(name string, quantity int) int {
+	removed := 0
+	for _, container := range p.Equipments.Containers {
+		removed += container.Remove(name, quantity-removed)
+	}
+	return removed
+}
+
+// FindItem は全ての入れ物から名前が一致するアイテムを探す
+func (p *Player) FindItem(name string) *Item {
+	for _, container := range p.Equipments.Containers {
+		if item := container.Find(name); item != nil {
+			return item
+		}
+	}
+	return nil
+}
+
+// ItemsByCategory は指定した分類のアイテムを全ての入れ物から集める
+func (p *Player) ItemsByCategory(category string) []*Item {
+	var items []*Item
+	for _, container := range p.Equipments.Containers {
+		for _, item := range container.Items {
+			if item.Category == category {
+				items = append(items, item)
+			}
+		}
+	}
+	return items
+}
+
+// CountItem は入れ物と装備品を合わせたアイテムの数を返す
+func (p *Player) CountItem(name string) int {
+	count := 0
+	for _, container := range p.Equipments.Containers {
+		count += container.Count(name)
+	}
+	e := p.Equipments
+	for _, weapon := range []*Weapon{e.Weapon1, e.Weapon2} {
+		if weapon != nil && weapon.Name == name {
+			count++
+		}
+	}
+	for _, armor := range []*Armor{e.Head, e.Body} {
+		if armor != nil && armor.Name == name {
+			count++
+		}
+	}
+	return count
+}
+
+// HasItem はアイテムを持っているか確認（選択肢などの条件判定はこれを使う）
+func (p *Player) HasItem(name string) bool {
+	return p.CountItem(name) > 0
+}
+
+// ConsumeItem はアイテムを1回分使う。使い切ったら入れ物から取り除く
+func (p *Player) ConsumeItem(item *Item) {
+	if item.Doses > 0 {
+		item.Doses--
+		if item.Doses > 0 {
+			return
+		}
+	}
+	container := p.Container(item.Slot)
+	if container == nil {
+		return
+	}
+	item.Quantity = item.Count() - 1
+	if item.Quantity <= 0 {
+		container.RemoveItem(item)
+	}
+}
+
+// NewItem は本で定義されたアイテムの複製を返す（定義がなければ名前だけのアイテム）
+func (gs *GameState) NewItem(name string) *Item {
+	if def, ok := gs.Items[name]; ok {
+		item := def
+		return &item
+	}
+	return &Item{Name: name, Slot: SlotBackpack}
+}
+
+// LoseContainer は入れ物の中身を全て失う（「バックパックを失った」など）
+func (p *Player) LoseContainer(name string) []*Item {
+	container := p.Container(name)
+	if container == nil {
+		return nil
+	}
+	lost := container.Items
+	container.Items = nil
+	return lost
+}
+
+// AddGold は所持金の上限まで金貨を加え、実際に加えた額を返す
+func (p *Player) AddGold(amount int) int {
+	before := p.Gold
+	p.Gold += amount
+	if p.MaxGold > 0 && p.Gold > p.MaxGold {
+		p.Gold = p.MaxGold
+	}
+	if p.Gold < 0 {
+		p.Gold = 0
+	}
+	return p.Gold - before
+}
+
+// GetItem はアイテムを手に入れる。入れ物がいっぱいなら何かを捨てるか尋ねる
+func (gs *GameState) GetItem(item *Item) bool {
+	for {
+		err := gs.Player.AddItem(item)
+		if err == nil {
+			fmt.Printf("%sを手に入れた\n", item.Name)
+			return true
+		}
+		if err != ErrContainerFull {
+			fmt.Println("エラー:", err)
+			return false
+		}
+
+		container := gs.Player.Container(item.Slot)
+		fmt.Printf("%sがいっぱいでこれ以上持てません (%d/%d)\n", container.Name, container.Used(), container.Capacity)
+		for i, held := range container.Items {
+			fmt.Printf("%d:%sを捨てる\n", i+1, held.Name)
+		}
+		input := gs.ReadLine(fmt.Sprintf("それ以外:%sを諦める\n", item.Name))
+		choiceNum, err := strconv.Atoi(input)
+		if err != nil || choiceNum < 1 || choiceNum > len(container.Items) {
+			fmt.Printf("%sを諦めた\n", item.Name)
+			return false
+		}
+		dropped := container.Items[choiceNum-1]
+		container.Remove(dropped.Name, 1)
+		fmt.Printf("%sを捨てた\n", dropped.Name)
+	}
+}
+
+// Acquire はアイテムを手に入れる。武器なら武器の枠に、防具なら装備箇所に、それ以外は入れ物に入れる
+// 手に入れられたら true（満杯で諦めた場合は false）
+func (gs *GameState) Acquire(item *Item) bool {
+	before := gs.Player.CountItem(item.Name)
+	switch item.Category {
+	case CategoryWeapon:
+		gs.NewWeapon(item.Name).Get(gs)
+	case CategoryArmor:
+		gs.NewArmor(item.Name).Get(gs)
+	default:
+		return gs.GetItem(item)
+	}
+	if gs.Player.CountItem(item.Name) == before {
+		return false
+	}
+	fmt.Printf("%sを手に入れた\n", item.Name)
+	return true
+}
@@ -0,0 +1,56 @@
+package game
+
+import "testing"
+
+func TestConsumeItemDoses(t *testing.T) {
+	tests := []struct {
+		name     string
+		doses    int
+		quantity []int // 1回に手に入れる個数
+		want     int   // 使える回数
+	}{
+		{"no doses", 0, []int{1, 1}, 2},
+		{"one potion", 2, []int{1}, 2},
+		{"two potions", 2, []int{1, 1}, 4},
+		{"two potions at once", 2, []int{2}, 4},
+		{"mixed", 3, []int{2, 1}, 9},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := NewPlayer()
+			for _, q := range tt.quantity {
+				item := &Item{Name: "Potion", Slot: SlotBackpack, Doses: tt.doses, Quantity: q}
+				if err := p.AddItem(item); err != nil {
+					t.Fatal(err)
+				}
+			}
+			uses := 0
+			for item := p.FindItem("Potion"); item != nil; item = p.FindItem("Potion") {
+				p.ConsumeItem(item)
+				uses++
+				if uses > 100 {
+					t.Fatal("item is never used up")
+				}
+			}
+			if uses != tt.want {
+				t.Errorf("used %d times, want %d", uses, tt.want)
+			}
+		})
+	}
+}
+
+func TestContainerAddCapacity(t *testing.T) {
+	c := &Container{Name: SlotBackpack, Capacity: 2}
+	if err := c.Add(&Item{Name: "Meal", Quantity: 2}); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Add(&Item{Name: "Meal"}); err != ErrContainerFull {
+		t.Errorf("got %v, want ErrContainerFull", err)
+	}
+	if got := c.Count("Meal"); got != 2 {
+		t.Errorf("Count = %d, want 2", got)
+	}
+	if got := c.Remove("Meal", 5); got != 2 || len(c.Items) != 0 {
+		t.Errorf("Remove = %d with %d left, want 2 with 0 left", got, len(c.Items))
+	}
+}
@@ -0,0 +1,90 @@
+package game
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// LootGold は金貨を表す落とし物の名前
+const LootGold = "Gold"
+
+// lootRemaining は拾わずに残っている数を返す
+func lootRemaining(gs *GameState, node Node, loot LootItem) int {
+	quantity := loot.Quantity
+	if quantity == 0 {
+		quantity = 1
+	}
+	return quantity - gs.TakenCount(node.ID, loot.Name)
+}
+
+// OfferLoot はセクションで見つけた物を表示し、プレイヤーが選んだ物を拾わせる
+// 置いていった物は記録され、再びセクションを訪れた時にまた拾える
+func OfferLoot(gs *GameState, node Node) error {
+	for {
+		var left []LootItem
+		for _, loot := range node.Loot {
+			if lootRemaining(gs, node, loot) > 0 {
+				left = append(left, loot)
+			}
+		}
+		if len(left) == 0 {
+			return nil
+		}
+
+		fmt.Println("\n見つけた物:")
+		for i, loot := range left {
+			if n := lootRemaining(gs, node, loot); n > 1 {
+				fmt.Printf("%d. %s x%d\n", i+1, loot.Name, n)
+			} else {
+				fmt.Printf("%d. %s\n", i+1, loot.Name)
+			}
+		}
+		fmt.Println("a. 全部拾う")
+		fmt.Println("0. 先へ進む")
+
+		input, err := gs.ReadChoice("選択してください (番号): ")
+		if err != nil {
+			return err // load や back で移動した
+		}
+		switch strings.ToLower(input) {
+		case "0":
+			return nil
+		case "a":
+			for _, loot := range left {
+				for lootRemaining(gs, node, loot) > 0 {
+					if !takeLoot(gs, node, loot) {
+						break // 持ちきれなかった
+					}
+				}
+			}
+			continue
+		}
+		choiceNum, err := strconv.Atoi(input)
+		if err != nil || choiceNum < 1 || choiceNum > len(left) {
+			fmt.Printf("0〜%dの番号か a を入力してください。\n", len(left))
+			continue
+		}
+		takeLoot(gs, node, left[choiceNum-1])
+	}
+}
+
+// takeLoot は金貨なら持てるだけ、アイテムなら1個拾う。拾えなかったら false
+func takeLoot(gs *GameState, node Node, loot LootItem) bool {
+	if loot.Name == LootGold {
+		gained := gs.Player.AddGold(lootRemaining(gs, node, loot))
+		if gained == 0 {
+			fmt.Println("これ以上金貨を持てない。")
+			return false
+		}
+		gs.Take(node.ID, loot.Name, gained)
+		gs.Logf("セクション%s: %dゴールドを拾った", node.ID, gained)
+		return true
+	}
+	if !gs.Acquire(gs.NewItem(loot.Name)) {
+		return false
+	}
+	gs.Take(node.ID, loot.Name, 1)
+	gs.Logf("セクション%s: %sを拾った", node.ID, loot.Name)
+	return true
+}
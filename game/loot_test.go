@@ -0,0 +1,63 @@
+package game
+
+import (
+	"bufio"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLootTakenOnce(t *testing.T) {
+	node := Node{ID: "5", Type: "story", Loot: []LootItem{
+		{Name: LootGold, Quantity: 3},
+		{Name: "Dagger"},
+	}}
+	gs := &GameState{
+		Player: NewPlayer(),
+		Reader: bufio.NewReader(strings.NewReader("a\n")), // 全部拾う
+		Nodes:  map[string]Node{"5": node, "6": {ID: "6", Type: "story"}},
+	}
+
+	enter(t, gs, "5")
+	if err := OfferLoot(gs, node); err != nil {
+		t.Fatal(err)
+	}
+	if gs.Player.Gold != 3 || !gs.Player.HasItem("Dagger") {
+		t.Fatalf("Gold = %d, Dagger = %v, want 3 and true", gs.Player.Gold, gs.Player.HasItem("Dagger"))
+	}
+
+	// もう一度訪れても拾う物は残っていない（入力を読まずに戻る）
+	if err := OfferLoot(gs, node); err != nil {
+		t.Fatal(err)
+	}
+	if gs.Player.Gold != 3 || gs.Player.CountItem("Dagger") != 1 {
+		t.Errorf("took loot twice: Gold = %d, Dagger = %d", gs.Player.Gold, gs.Player.CountItem("Dagger"))
+	}
+
+	// 次のセクションで保存して読み込んでも拾った記録は残る
+	enter(t, gs, "6")
+	path := filepath.Join(t.TempDir(), "save.toml")
+	if err := gs.Save(path); err != nil {
+		t.Fatal(err)
+	}
+	loaded := &GameState{Nodes: gs.Nodes}
+	if err := loaded.Load(path); err != nil {
+		t.Fatal(err)
+	}
+	for _, loot := range node.Loot {
+		if n := lootRemaining(loaded, node, loot); n != 0 {
+			t.Errorf("after load: %s remaining = %d, want 0", loot.Name, n)
+		}
+	}
+
+	// back で拾う前に戻れば、拾った物も記録も元に戻る
+	if err := gs.Back(); err != nil {
+		t.Fatal(err)
+	}
+	if gs.Player.Gold != 0 || gs.Player.HasItem("Dagger") {
+		t.Errorf("after back: Gold = %d, Dagger = %v, want 0 and false", gs.Player.Gold, gs.Player.HasItem("Dagger"))
+	}
+	if n := lootRemaining(gs, node, node.Loot[0]); n != 3 {
+		t.Errorf("after back: Gold remaining = %d, want 3", n)
+	}
+}
@@ -0,0 +1,84 @@
+package game
+
+// ルールシステムの登録
+//
+// 各システムのパッケージは init で RegisterSystem を呼んで自分を登録する。
+// 使うシステムの一覧は main パッケージの systems.go に blank import で並べる。
+// 新しいシステムを加える時は systems.go に1行足すだけで、main.go は変更しない。
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// SystemInfo はルールシステムの登録内容
+type SystemInfo struct {
+	Name        string   // 設定ファイルの system に書く名前 "lonewolf"
+	Description string   // --list-systems で表示する説明
+	ConfigFiles []string // 設定ディレクトリに必要なファイル "combat_result_table.toml"
+	New         func(configDir string) (GameSystem, error)
+}
+
+// systems は登録されたルールシステム
+var systems = make(map[string]SystemInfo)
+
+// RegisterSystem はルールシステムを登録する。各システムのパッケージが init で呼ぶ
+// 名前が空か、同じ名前を二度登録した場合は panic する
+func RegisterSystem(info SystemInfo) {
+	if info.Name == "" || info.New == nil {
+		panic("game: RegisterSystem needs a name and a factory")
+	}
+	if _, dup := systems[info.Name]; dup {
+		panic("game: system " + info.Name + " registered twice")
+	}
+	systems[info.Name] = info
+}
+
+// Systems は登録されたルールシステムを名前順に返す
+func Systems() []SystemInfo {
+	list := make([]SystemInfo, 0, len(systems))
+	for _, info := range systems {
+		list = append(list, info)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list
+}
+
+// NewSystem は名前で登録されたルールシステムを作る
+// 必要なファイルが設定ディレクトリに無ければ作る前にエラーを返す
+func NewSystem(name, configDir string) (GameSystem, error) {
+	info, ok := systems[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown system %q (available: %s)", name, strings.Join(SystemNames(), ", "))
+	}
+	for _, file := range info.ConfigFiles {
+		path := filepath.Join(configDir, file)
+		if _, err := os.Stat(path); err != nil {
+			return nil, fmt.Errorf("system %s: %w", name, err)
+		}
+	}
+	return info.New(configDir)
+}
+
+// SystemNames は登録されたルールシステムの名前を名前順に返す
+func SystemNames() []string {
+	var names []string
+	for _, info := range Systems() {
+		names = append(names, info.Name)
+	}
+	return names
+}
+
+// ListSystems は登録されたルールシステムの一覧を表示する
+func ListSystems() {
+	fmt.Println("--- ルールシステム ---")
+	for _, info := range Systems() {
+		fmt.Printf("  %-16s %s\n", info.Name, info.Description)
+		if len(info.ConfigFiles) > 0 {
+			fmt.Printf("  %-16s 必要なファイル: %s\n", "", strings.Join(info.ConfigFiles, ", "))
+		}
+	}
+}
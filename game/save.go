@@ -0,0 +1,125 @@
+package game
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+
+	"github.com/BurntSushi/toml"
+)
+
+// DefaultSaveFile はファイル名を指定しなかった時の保存先
+const DefaultSaveFile = "save.toml"
+
+// maxHistory は back で戻れるセクションの数
+const maxHistory = 50
+
+// SaveData はセクションに入った時点のゲームの状態（セーブと back に使う）
+type SaveData struct {
+	CurrentNodeID string         `toml:"current_node_id"`
+	Player        *Player        `toml:"player"`
+	Flags         map[string]int `toml:"flags,omitempty"`
+	Visits        map[string]int `toml:"visits,omitempty"`
+	Taken         map[string]int `toml:"taken,omitempty"`
+	Log           []string       `toml:"log,omitempty"`
+}
+
+// checkpoint はセクションに入る直前の状態を記録する
+func (gs *GameState) checkpoint() error {
+	var buf bytes.Buffer
+	data := SaveData{
+		CurrentNodeID: gs.CurrentNodeID,
+		Player:        gs.Player,
+		Flags:         gs.Flags,
+		Visits:        gs.Visits,
+		Taken:         gs.Taken,
+		Log:           gs.Log,
+	}
+	if err := toml.NewEncoder(&buf).Encode(data); err != nil {
+		return fmt.Errorf("failed to record section %s: %w", gs.CurrentNodeID, err)
+	}
+	gs.history = append(gs.history, buf.String())
+	if len(gs.history) > maxHistory {
+		gs.history = gs.history[1:]
+	}
+	return nil
+}
+
+// restore は記録した状態に戻す
+func (gs *GameState) restore(encoded string) error {
+	var data SaveData
+	if _, err := toml.Decode(encoded, &data); err != nil {
+		return fmt.Errorf("invalid save data: %w", err)
+	}
+	if data.Player == nil || data.Player.Equipments == nil {
+		return fmt.Errorf("save data has no player")
+	}
+	if _, ok := gs.Nodes[data.CurrentNodeID]; !ok {
+		return fmt.Errorf("save data refers to unknown node %q", data.CurrentNodeID)
+	}
+	if data.Player.Stats == nil {
+		data.Player.Stats = make(map[string]int)
+	}
+	if data.Player.Attributes == nil {
+		data.Player.Attributes = make(map[string]bool)
+	}
+	if data.Player.Equipments.Containers == nil {
+		data.Player.Equipments.Containers = make(map[string]*Container)
+	}
+	gs.Player = data.Player
+	gs.CurrentNodeID = data.CurrentNodeID
+	gs.Flags = data.Flags
+	gs.Visits = data.Visits
+	gs.Taken = data.Taken
+	gs.Log = data.Log
+	gs.InCombat = false
+	return nil
+}
+
+// Save は今のセクションに入った時点の状態をファイルに保存する
+func (gs *GameState) Save(path string) error {
+	if len(gs.history) == 0 {
+		return fmt.Errorf("nothing to save yet")
+	}
+	if err := os.WriteFile(path, []byte(gs.history[len(gs.history)-1]), 0644); err != nil {
+		return fmt.Errorf("failed to save: %w", err)
+	}
+	fmt.Printf("セクション%sの始めから再開できるよう%sに保存しました。\n", gs.CurrentNodeID, path)
+	return nil
+}
+
+// Load はファイルから状態を読み込み、保存したセクションから再開する
+func (gs *GameState) Load(path string) error {
+	encoded, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to load: %w", err)
+	}
+	if err := gs.restore(string(encoded)); err != nil {
+		return err
+	}
+	gs.history = nil // 読み込んだセクションに入る時に記録し直す
+	fmt.Printf("%sを読み込みました。セクション%sから再開します。\n", path, gs.CurrentNodeID)
+	return nil
+}
+
+// Back は1つ前のセクションに入った時点の状態に戻る
+func (gs *GameState) Back() error {
+	if len(gs.history) < 2 {
+		return fmt.Errorf("there is no previous section")
+	}
+	previous := gs.history[len(gs.history)-2]
+	if err := gs.restore(previous); err != nil {
+		return err
+	}
+	gs.history = gs.history[:len(gs.history)-2] // 戻ったセクションに入る時に記録し直す
+	fmt.Printf("セクション%sに戻ります。\n", gs.CurrentNodeID)
+	return nil
+}
+
+// saveFileName は save / load の引数からファイル名を決める
+func saveFileName(arg string) string {
+	if arg == "" {
+		return DefaultSaveFile
+	}
+	return arg
+}
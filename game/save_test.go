@@ -0,0 +1,87 @@
+package game
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// enter は Run と同じようにセクションに入った時点の状態を記録する
+func enter(t *testing.T, gs *GameState, id string) {
+	t.Helper()
+	gs.CurrentNodeID = id
+	if err := gs.checkpoint(); err != nil {
+		t.Fatal(err)
+	}
+	gs.Visit(id)
+}
+
+func TestSaveLoadBack(t *testing.T) {
+	gs := &GameState{
+		Player: NewPlayer(),
+		Nodes: map[string]Node{
+			"1": {ID: "1", Type: "story"},
+			"2": {ID: "2", Type: "encounter", Enemies: []*Enemy{{Name: "Giak", HP: 9, CS: 10}}},
+		},
+	}
+	gs.Player.Stats["HP"] = 20
+	gs.Player.AddItem(&Item{Name: "Meal", Quantity: 2})
+	gs.SetFlag("ORCHID")
+	gs.Visit("0")
+
+	enter(t, gs, "1")
+	path := filepath.Join(t.TempDir(), "save.toml")
+	if err := gs.Save(path); err != nil {
+		t.Fatal(err)
+	}
+	saved := map[string]int{"0": 1} // 記録はセクション1に入る直前の状態
+
+	// セクション2で戦って状態が変わる
+	enter(t, gs, "2")
+	enemies := CopyEnemies(gs.Nodes["2"].Enemies)
+	enemies[0].HP = 0
+	gs.Player.Stats["HP"] = 3
+	gs.Player.RemoveItem("Meal", 2)
+	gs.SetFlag("GIAK")
+
+	check := func(step string) {
+		t.Helper()
+		if gs.CurrentNodeID != "1" {
+			t.Errorf("%s: CurrentNodeID = %q, want 1", step, gs.CurrentNodeID)
+		}
+		if gs.Player.Stats["HP"] != 20 {
+			t.Errorf("%s: HP = %d, want 20", step, gs.Player.Stats["HP"])
+		}
+		if got := gs.Player.CountItem("Meal"); got != 2 {
+			t.Errorf("%s: Meal = %d, want 2", step, got)
+		}
+		if !reflect.DeepEqual(gs.Flags, map[string]int{"ORCHID": 1}) {
+			t.Errorf("%s: Flags = %v, want only ORCHID", step, gs.Flags)
+		}
+		if !reflect.DeepEqual(gs.Visits, saved) {
+			t.Errorf("%s: Visits = %v, want %v", step, gs.Visits, saved)
+		}
+		if hp := CopyEnemies(gs.Nodes["2"].Enemies)[0].HP; hp != 9 {
+			t.Errorf("%s: enemy HP = %d, want 9", step, hp)
+		}
+	}
+
+	if err := gs.Load(path); err != nil {
+		t.Fatal(err)
+	}
+	check("load")
+
+	// 読み込んだセクションに入り直してから進み、back で戻る
+	enter(t, gs, "1")
+	enter(t, gs, "2")
+	gs.Player.Stats["HP"] = 3
+	gs.SetFlag("GIAK")
+	if err := gs.Back(); err != nil {
+		t.Fatal(err)
+	}
+	check("back")
+
+	if err := gs.Back(); err == nil {
+		t.Error("Back at the first section succeeded")
+	}
+}
@@ -0,0 +1,121 @@
+package game
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// HandleShopNode は店のセクションを処理する。買い物と売却を繰り返し、店を出たら NextNodeID へ進む
+func HandleShopNode(gs *GameState, node Node) error {
+	if node.NextNodeID == "" {
+		return fmt.Errorf("shop node %s has no next_node_id", node.ID)
+	}
+	for {
+		fmt.Printf("\n--- 店 --- 所持金：%dゴールド\n", gs.Player.Gold)
+		for i, entry := range node.Shop {
+			fmt.Printf("%d. %s\n", i+1, shopLabel(gs, node, entry))
+		}
+		fmt.Println("s. 売る")
+		fmt.Println("0. 店を出る")
+
+		input, err := gs.ReadChoice("選択してください (番号): ")
+		if err != nil {
+			return err // load や back で移動した
+		}
+		switch strings.ToLower(input) {
+		case "0":
+			gs.CurrentNodeID = node.NextNodeID
+			return nil
+		case "s":
+			sellToShop(gs, node)
+			continue
+		}
+		choiceNum, err := strconv.Atoi(input)
+		if err != nil || choiceNum < 1 || choiceNum > len(node.Shop) {
+			fmt.Printf("0〜%dの番号か s を入力してください。\n", len(node.Shop))
+			continue
+		}
+		buyFromShop(gs, node, node.Shop[choiceNum-1])
+	}
+}
+
+// shopStock は残りの在庫を返す（無制限なら-1）
+func shopStock(gs *GameState, node Node, entry ShopItem) int {
+	if entry.Stock <= 0 {
+		return -1
+	}
+	return entry.Stock - gs.TakenCount(node.ID, entry.Name)
+}
+
+// shopLabel は品物の値段と在庫を表示用にまとめる
+func shopLabel(gs *GameState, node Node, entry ShopItem) string {
+	if entry.Price <= 0 {
+		return fmt.Sprintf("%s（買い取りのみ %dゴールド）", entry.Name, entry.SellPrice)
+	}
+	label := fmt.Sprintf("%s %dゴールド", entry.Name, entry.Price)
+	switch stock := shopStock(gs, node, entry); {
+	case stock == 0:
+		label += "（売り切れ）"
+	case stock > 0:
+		label += fmt.Sprintf("（残り%d）", stock)
+	}
+	return label
+}
+
+// buyFromShop は品物を1つ買う。持ちきれなければ代金は払わない
+func buyFromShop(gs *GameState, node Node, entry ShopItem) {
+	switch {
+	case entry.Price <= 0:
+		fmt.Printf("%sは売っていない。\n", entry.Name)
+		return
+	case shopStock(gs, node, entry) == 0:
+		fmt.Printf("%sは売り切れだ。\n", entry.Name)
+		return
+	case gs.Player.Gold < entry.Price:
+		fmt.Printf("金貨が足りない。（%dゴールド必要）\n", entry.Price)
+		return
+	}
+	if !gs.Acquire(gs.NewItem(entry.Name)) {
+		return
+	}
+	gs.Player.AddGold(-entry.Price)
+	gs.Take(node.ID, entry.Name, 1)
+	gs.Logf("セクション%s: %sを%dゴールドで買った", node.ID, entry.Name, entry.Price)
+}
+
+// sellToShop は店が買い取る持ち物を選んで売る
+func sellToShop(gs *GameState, node Node) {
+	var offers []ShopItem
+	for _, entry := range node.Shop {
+		if entry.SellPrice > 0 && gs.Player.HasItem(entry.Name) {
+			offers = append(offers, entry)
+		}
+	}
+	if len(offers) == 0 {
+		fmt.Println("この店が買い取る物を持っていない。")
+		return
+	}
+
+	fmt.Println("\n売る物:")
+	for i, entry := range offers {
+		fmt.Printf("%d. %s %dゴールド\n", i+1, entry.Name, entry.SellPrice)
+	}
+	fmt.Println("それ以外: やめる")
+	choiceNum, err := strconv.Atoi(gs.ReadLine("選択してください (番号): "))
+	if err != nil || choiceNum < 1 || choiceNum > len(offers) {
+		return
+	}
+
+	entry := offers[choiceNum-1]
+	if gs.Player.RemoveItem(entry.Name, 1) == 0 {
+		gs.dropEquipment(entry.Name)
+	}
+	if gained := gs.Player.AddGold(entry.SellPrice); gained < entry.SellPrice {
+		fmt.Printf("金貨を持ちきれず、%dゴールドしか受け取れなかった。\n", gained)
+	}
+	if entry.Price > 0 {
+		gs.Take(node.ID, entry.Name, -1) // 売った物は店の在庫に戻る
+	}
+	gs.Logf("セクション%s: %sを%dゴールドで売った", node.ID, entry.Name, entry.SellPrice)
+}
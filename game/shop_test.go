@@ -0,0 +1,91 @@
+package game
+
+import (
+	"bufio"
+	"strings"
+	"testing"
+)
+
+// newShopState は input を入力として読む GameState を返す
+func newShopState(input string) *GameState {
+	return &GameState{
+		Player: NewPlayer(),
+		Reader: bufio.NewReader(strings.NewReader(input)),
+	}
+}
+
+func TestBuyFromShop(t *testing.T) {
+	node := Node{ID: "40", Type: "shop", NextNodeID: "41", Shop: []ShopItem{
+		{Name: "Meal", Price: 2, Stock: 1},
+		{Name: "Gem", SellPrice: 5},
+		{Name: "Rope", Price: -1},
+	}}
+
+	gs := newShopState("")
+	gs.Player.Gold = 10
+	buyFromShop(gs, node, node.Shop[0])
+	buyFromShop(gs, node, node.Shop[0]) // 売り切れ
+	if got := gs.Player.CountItem("Meal"); got != 1 || gs.Player.Gold != 8 {
+		t.Errorf("Meal = %d, Gold = %d, want 1 and 8", got, gs.Player.Gold)
+	}
+	if got := shopStock(gs, node, node.Shop[0]); got != 0 {
+		t.Errorf("stock = %d, want 0", got)
+	}
+
+	// 値段が0以下の品物は買えない
+	for _, entry := range node.Shop[1:] {
+		buyFromShop(gs, node, entry)
+		if gs.Player.HasItem(entry.Name) || gs.Player.Gold != 8 {
+			t.Errorf("bought %s with price %d", entry.Name, entry.Price)
+		}
+	}
+
+	// 金貨が足りなければ買えない
+	gs.Player.Gold = 1
+	buyFromShop(gs, node, ShopItem{Name: "Sword", Price: 5})
+	if gs.Player.HasItem("Sword") || gs.Player.Gold != 1 {
+		t.Error("bought Sword without enough gold")
+	}
+}
+
+func TestBuyFromShopFullBackpack(t *testing.T) {
+	node := Node{ID: "40", Shop: []ShopItem{{Name: "Meal", Price: 2, Stock: 3}}}
+	gs := newShopState("\n") // 何も捨てずに諦める
+	gs.Player.Gold = 10
+	gs.Player.Container(SlotBackpack).Capacity = 1
+	gs.Player.AddItem(&Item{Name: "Rope"})
+
+	buyFromShop(gs, node, node.Shop[0])
+	if gs.Player.HasItem("Meal") || gs.Player.Gold != 10 {
+		t.Errorf("Meal = %v, Gold = %d, want none bought and 10 gold", gs.Player.HasItem("Meal"), gs.Player.Gold)
+	}
+	if got := shopStock(gs, node, node.Shop[0]); got != 3 {
+		t.Errorf("stock = %d, want 3", got)
+	}
+}
+
+func TestSellToShop(t *testing.T) {
+	node := Node{ID: "40", Shop: []ShopItem{{Name: "Meal", Price: 2, Stock: 1, SellPrice: 5}}}
+	gs := newShopState("1\n")
+	gs.Player.Gold = 8
+	gs.Player.MaxGold = 10
+	gs.Player.AddItem(&Item{Name: "Meal"})
+	gs.Take(node.ID, "Meal", 1) // 店で買った分
+
+	sellToShop(gs, node)
+	if gs.Player.HasItem("Meal") {
+		t.Error("Meal was not sold")
+	}
+	if gs.Player.Gold != 10 {
+		t.Errorf("Gold = %d, want 10 (capped)", gs.Player.Gold)
+	}
+	if got := shopStock(gs, node, node.Shop[0]); got != 1 {
+		t.Errorf("stock = %d, want 1 after selling back", got)
+	}
+
+	// 店が買い取る物を持っていなければ何も読まない
+	sellToShop(gs, node)
+	if gs.Player.Gold != 10 {
+		t.Errorf("Gold = %d, want 10", gs.Player.Gold)
+	}
+}
@@ -2,6 +2,7 @@ package game
 
 import (
 	"bufio"
+	"errors"
 	"fmt"
 
 	//"new-gamebook/lonewolf"
@@ -17,38 +18,99 @@ type GameSystem interface {
 	UpdatePlayer(gs *GameState, action string) error
 }
 
+// DeathRules はプレイヤーが倒れたかを判定する GameSystem が実装する
+// セクションの効果で倒れたら "game_over" のセクションへ送る
+type DeathRules interface {
+	IsDead(p *Player) bool
+}
+
 // Node はゲームの各ステップ（ノード）を表す
 type Node struct {
-	ID       string    `toml:"id"`
-	Type     string    `toml:"type"`
-	Text     string    `toml:"text"`
-	Choices  []Choice  `toml:"choices,omitempty"`
-	Enemies  []*Enemy  `toml:"enemies,omitempty"`
-	Outcomes []Outcome `toml:"outcomes,omitempty"`
+	ID              string           `toml:"id"`
+	Type            string           `toml:"type"`
+	Text            string           `toml:"text"`
+	Choices         []Choice         `toml:"choices,omitempty"`
+	Enemies         []*Enemy         `toml:"enemies,omitempty"`
+	Outcomes        []Outcome        `toml:"outcomes,omitempty"`
+	CombatModifiers []CombatModifier `toml:"combat_modifiers,omitempty"` // 戦闘中の条件付き戦闘力修正
+	Unarmed         bool             `toml:"unarmed,omitempty"`          // 素手で戦わなければならない
+	EvadeRound      int              `toml:"evade_round,omitempty"`      // 逃走できる最初のラウンド（"combat_evaded" の結果が必要）
+	NoEscape        bool             `toml:"no_escape,omitempty"`        // この戦いからは逃げられない
+	RoundLimit      int              `toml:"round_limit,omitempty"`      // このラウンド数で戦闘を打ち切る（"round_limit" の結果へ）
+	CombatMode      string           `toml:"combat_mode,omitempty"`      // "sequential"（既定）または "simultaneous"
+	Effects         []string         `toml:"effects,omitempty"`          // セクションに入った時の効果 "HP-2" "gold+6" "get Dagger" など
+	LoseBackpack    bool             `toml:"lose_backpack,omitempty"`    // バックパックとその中身を失う
+	EatMeal         bool             `toml:"eat_meal,omitempty"`         // ここで食事をしなければならない
+	NoHunting       bool             `toml:"no_hunting,omitempty"`       // 狩猟の技能で食事を免除できない
+	NextNodeID      string           `toml:"next_node_id,omitempty"`     // 選択肢のないセクション（店など）の次のセクション
+	Shop            []ShopItem       `toml:"shop,omitempty"`             // 店の品揃え（type = "shop"）
+	Loot            []LootItem       `toml:"loot,omitempty"`             // 落ちている物（選んで拾える）
+	SaveAbility     string           `toml:"save_ability,omitempty"`     // セーヴィング・スローの能力値 "DEX"（d20系）
+	SaveDC          int              `toml:"save_dc,omitempty"`          // セーヴィング・スローの難易度（d20系）
 }
 
 // Choice は選択肢を表す
 type Choice struct {
 	Description        string            `toml:"description"`
 	NextNodeID         string            `toml:"next_node_id"`
+	If                 string            `toml:"if,omitempty"` // 条件式 例: discipline "Sixth Sense" AND gold >= 10
 	RequiredDiscipline string            `toml:"required_discipline,omitempty"`
 	RequiredItem       string            `toml:"required_item,omitempty"`
 	Conditions         map[string]string `toml:"conditions,omitempty"`
+	Expr               Condition         `toml:"-"` // 読み込み時に全ての条件をまとめて解析したもの
 }
 
 // Enemy は戦闘の敵キャラクター
 type Enemy struct {
-	Name string `toml:"Name"`
-	HP   int    `toml:"HP"`
-	CS   int    `toml:"CS"`
+	Name            string `toml:"Name"`
+	HP              int    `toml:"HP"`
+	CS              int    `toml:"CS"`
+	MindblastImmune bool   `toml:"MindblastImmune,omitempty"` // マインドブラストが効かない
+	Mindforce       int    `toml:"Mindforce,omitempty"`       // マインドシールドがなければ毎ラウンド受けるダメージ
+	Undead          bool   `toml:"Undead,omitempty"`          // アンデッド（ソマースウェルドでダメージ2倍）
+	AC              int    `toml:"AC,omitempty"`              // 防御値（d20系）
+	Attack          int    `toml:"Attack,omitempty"`          // 攻撃ロールの修正値（d20系）
+	Damage          string `toml:"Damage,omitempty"`          // ダメージのダイス "1d12+3"（d20系）
+	Initiative      int    `toml:"Initiative,omitempty"`      // イニシアチブの修正値（d20系）
+}
+
+// CombatModifier は条件付きの戦闘力修正を表す
+// 例: 「マインドシールドを持っていなければ戦闘力-2」
+type CombatModifier struct {
+	Description      string `toml:"description,omitempty"`
+	Value            int    `toml:"value"`
+	IfDiscipline     string `toml:"if_discipline,omitempty"`     // この技能を持っていれば適用
+	UnlessDiscipline string `toml:"unless_discipline,omitempty"` // この技能を持っていなければ適用
+	IfItem           string `toml:"if_item,omitempty"`           // このアイテムを持っていれば適用
+	UnlessItem       string `toml:"unless_item,omitempty"`       // このアイテムを持っていなければ適用
+	FromRound        int    `toml:"from_round,omitempty"`        // 適用開始ラウンド（0なら最初から）
+	ToRound          int    `toml:"to_round,omitempty"`          // 適用終了ラウンド（0なら最後まで）
+}
+
+// ShopItem は店で売り買いできるアイテム
+type ShopItem struct {
+	Name      string `toml:"name"`
+	Price     int    `toml:"price,omitempty"`      // 売値（0なら売っていない）
+	Stock     int    `toml:"stock,omitempty"`      // 在庫の数（0なら無制限）
+	SellPrice int    `toml:"sell_price,omitempty"` // 買い取り値（0なら買い取らない）
+}
+
+// LootItem はセクションで見つけた物（名前が "Gold" なら金貨）
+type LootItem struct {
+	Name     string `toml:"name"`
+	Quantity int    `toml:"quantity,omitempty"` // 個数（0なら1個）
 }
 
 // Outcome は遭遇戦の結果と次に進むノードを表す
 type Outcome struct {
-	Description  string `toml:"description,omitempty"`
-	Condition    string `toml:"condition,omitempty"`
-	ConditionInt []int  `toml:"condition_int,omitempty"`
-	NextNodeID   string `toml:"next_node_id"`
+	Description  string    `toml:"description,omitempty"`
+	Condition    string    `toml:"condition,omitempty"` // "combat_won", "combat_lost", "combat_evaded", "round_limit" など
+	ConditionInt []int     `toml:"condition_int,omitempty"`
+	MinRounds    int       `toml:"min_rounds,omitempty"` // 戦闘がこのラウンド数以上かかった場合のみ
+	MaxRounds    int       `toml:"max_rounds,omitempty"` // 戦闘がこのラウンド数以内に終わった場合のみ
+	If           string    `toml:"if,omitempty"`         // 追加の条件式
+	NextNodeID   string    `toml:"next_node_id"`
+	Expr         Condition `toml:"-"` // If を読み込み時に解析したもの
 }
 
 /*
@@ -99,7 +161,10 @@ type Item struct {
 type GameConfig struct {
 	System string                 `toml:"system"`
 	Player map[string]interface{} `toml:"player"`
+	Items  []Item                 `toml:"items,omitempty"` // 本で使うアイテムの定義
 	Nodes  []Node                 `toml:"nodes"`
+
+	LockedChoices string `toml:"locked_choices,omitempty"` // 条件を満たしていない選択肢を "show"（理由付き）か "hide"（隠す）か
 }
 
 // GameState はゲームの状態を保持
@@ -108,7 +173,24 @@ type GameState struct {
 	CurrentNodeID string
 	Nodes         map[string]Node
 	Reader        *bufio.Reader
-	System        GameSystem // System フィールドを追加
+	System        GameSystem      // System フィールドを追加
+	CombatRounds  int             // 直前の戦闘にかかったラウンド数
+	InCombat      bool            // 戦闘中かどうか（食事やポーションの制限に使う）
+	Items         map[string]Item // 本で定義されたアイテム（名前で引く）
+	Log           []string        // セクションの効果などの記録
+	Flags         map[string]int  // 合言葉や出来事のフラグとカウンター（セーブに含める）
+	Visits        map[string]int  // 訪れたセクションと回数
+	Taken         map[string]int  // セクションから持ち出したアイテムの数（店の在庫などに使う）
+	LockedChoices string          // 条件を満たしていない選択肢の扱い（LockedChoicesShow / LockedChoicesHide）
+	commands      map[string]Command
+	history       []string // セクションに入った時点の状態（back で戻る）
+}
+
+// Logf は出来事を表示して記録する
+func (gs *GameState) Logf(format string, args ...interface{}) {
+	entry := fmt.Sprintf(format, args...)
+	gs.Log = append(gs.Log, entry)
+	fmt.Println("【" + entry + "】")
 }
 
 // display_status はプレイヤーの状態を表示
@@ -149,30 +231,8 @@ func (gs *GameState) DisplayStatus() {
 		fmt.Println("  属性データがありません。")
 	}
 
-	// Inventory の表示
-	fmt.Println("インベントリ:")
-	if //gs.Player.Inventory != nil &&
-	len(gs.Player.Equipments.Backpack) > 0 {
-		for _, item := range gs.Player.Equipments.Backpack {
-			fmt.Printf("  - %s\n", item.Name)
-		}
-	} else {
-		fmt.Println("  アイテムがありません。")
-	}
-
 	// Equipment の表示
-	fmt.Println("武器") // "Equipment" を「装備」に変更
-	if gs.Player.Equipments.Currentweapon == 0 {
-		fmt.Println("  装備品がありません。")
-	} else if gs.Player.Equipments.Currentweapon == 1 {
-		fmt.Printf("装備：　%s\n", gs.Player.Equipments.Weapon1.Name)
-		fmt.Printf("予備：　%s\n", gs.Player.Equipments.Weapon2.Name)
-	} else if gs.Player.Equipments.Currentweapon == 2 {
-		fmt.Printf("装備：　%s\n", gs.Player.Equipments.Weapon2.Name)
-		fmt.Printf("予備：　%s\n", gs.Player.Equipments.Weapon1.Name)
-	} else {
-		fmt.Println("  装備品がありません。")
-	}
+	gs.displayWeapons()
 
 	fmt.Println("防具") // "Equipment" を「装備」に変更
 	if gs.Player.Equipments.Head == nil {
@@ -186,31 +246,73 @@ func (gs *GameState) DisplayStatus() {
 		fmt.Printf("体：　%s\n", gs.Player.Equipments.Body.Name)
 	}
 
-	fmt.Println("バックパック")
-	if len(gs.Player.Equipments.Backpack) == 0 {
-		fmt.Println("バックパックは空です")
-	} else {
-		for _, item := range gs.Player.Equipments.Backpack {
-			fmt.Printf("-%s\n", item.Name)
+	gs.DisplayInventory()
+	gs.displayFlags()
+
+	fmt.Println("--- ステータス ---")
+}
+
+// DisplayInventory は入れ物ごとの持ち物と所持金を表示
+func (gs *GameState) DisplayInventory() {
+	fmt.Println("持ち物")
+	for name, container := range gs.Player.Equipments.Containers {
+		if container.Capacity > 0 {
+			fmt.Printf("%s (%d/%d)\n", name, container.Used(), container.Capacity)
+		} else {
+			fmt.Println(name)
+		}
+		if len(container.Items) == 0 {
+			fmt.Println("  空です")
+		}
+		for _, item := range container.Items {
+			if item.Count() > 1 {
+				fmt.Printf("-%s x%d\n", item.Name, item.Count())
+			} else {
+				fmt.Printf("-%s\n", item.Name)
+			}
 		}
 	}
 
-	fmt.Printf("所持金：%dゴールド", gs.Player.Gold)
-
-	fmt.Println("--- ステータス ---")
+	if gs.Player.MaxGold > 0 {
+		fmt.Printf("所持金：%d/%dゴールド\n", gs.Player.Gold, gs.Player.MaxGold)
+	} else {
+		fmt.Printf("所持金：%dゴールド\n", gs.Player.Gold)
+	}
 }
 
 // Run はゲームループを開始
 func (gs *GameState) Run() {
-	gs.System.MakingPlayer(gs)
+	gs.setupCommands()
+	if err := gs.System.MakingPlayer(gs); err != nil {
+		fmt.Println("エラー:", err)
+		return
+	}
+	fmt.Println("（help でコマンドの一覧を表示します）")
 	for {
 		node, exists := gs.Nodes[gs.CurrentNodeID]
 		if !exists {
 			fmt.Println("\nエラー: 存在しないノードIDに到達しました:", gs.CurrentNodeID)
 			break
 		}
+		if err := gs.checkpoint(); err != nil {
+			fmt.Println("エラー:", err)
+			break
+		}
+		gs.Visit(node.ID)
 
-		if err := gs.System.HandleNode(gs, node); err != nil { // gs.Config.System → gs.System
+		if err := gs.ApplyNodeEffects(node); err != nil {
+			fmt.Println("エラー:", err)
+			break
+		}
+		if rules, ok := gs.System.(DeathRules); ok && node.Type != "end" && rules.IsDead(gs.Player) {
+			fmt.Println("\nあなたは力尽きた…")
+			gs.CurrentNodeID = "game_over"
+			continue
+		}
+
+		if err := gs.System.HandleNode(gs, node); errors.Is(err, ErrJump) {
+			continue // load や back で別のセクションに移った
+		} else if err != nil { // gs.Config.System → gs.System
 			fmt.Println("エラー:", err)
 			break
 		}
@@ -219,15 +321,18 @@ func (gs *GameState) Run() {
 			fmt.Println("ゲーム終了。")
 			break
 		}
+		gs.Player.ExpireEffects(DurationSections)
 	}
 }
 
 // こちらの方が良いのでは？
 type Player struct {
-	Stats      map[string]int
-	Attributes map[string]bool
-	Equipments *Equipment
-	Gold       int
+	Stats         map[string]int
+	Attributes    map[string]bool
+	Equipments    *Equipment
+	Gold          int
+	MaxGold       int            //所持金の上限　0なら無制限
+	ActiveEffects []ActiveEffect //期限付きで有効な効果
 }
 
 type Equipment struct {
@@ -237,7 +342,7 @@ type Equipment struct {
 	Weapon1       *Weapon
 	Weapon2       *Weapon
 	Shield        bool
-	Backpack      []*Item
+	Containers    map[string]*Container //バックパックなどの入れ物　Item.Slotで入れ先を決める
 }
 
 type Inventory interface {
@@ -251,39 +356,71 @@ type Weapon struct {
 	Name    string
 	Slot    string //Weapon1 Weapon2
 	CSBonus int    //いるかなぁ？
+	Damage  string //ダメージのダイス "1d8"　d20系のシステムで使用
 }
 
+// Get は武器を空いている枠に入れる。素手なら最初に手に入れた武器を構える
 func (w Weapon) Get(gs *GameState) {
-	if gs.Player.Equipments.Weapon1 == nil {
-		gs.Player.Equipments.Weapon1 = &w
-	} else if gs.Player.Equipments.Weapon2 == nil {
-		gs.Player.Equipments.Weapon2 = &w
+	e := gs.Player.Equipments
+	if e.Weapon1 == nil {
+		w.Slot = "Weapon1"
+		e.Weapon1 = &w
+	} else if e.Weapon2 == nil {
+		w.Slot = "Weapon2"
+		e.Weapon2 = &w
 	} else {
 		for { //CS変更は後で書く
-			fmt.Printf("これ以上持てません\n1:%sを捨てる\n2:%sを捨てる\n%sを諦める\n",
-				gs.Player.Equipments.Weapon1.Name, gs.Player.Equipments.Weapon2.Name, w.Name)
-
-			input, _ := gs.Reader.ReadString('\n')
-			input = strings.TrimSpace(input)
+			input := gs.ReadLine(fmt.Sprintf("これ以上持てません\n1:%sを捨てる\n2:%sを捨てる\n%sを諦める\n",
+				gs.Player.Equipments.Weapon1.Name, gs.Player.Equipments.Weapon2.Name, w.Name))
 			choiceNum, err := strconv.Atoi(input)
 
 			if err == nil && choiceNum == 1 {
 				fmt.Printf("%sを捨てて%sに持ち替えた\n", gs.Player.Equipments.Weapon1.Name, w.Name)
+				w.Slot = "Weapon1"
 				gs.Player.Equipments.Weapon1 = &w
-				//CS更新
 				break
 			} else if err == nil && choiceNum == 2 {
 				fmt.Printf("%sを捨てて%sに持ち替えた\n", gs.Player.Equipments.Weapon2.Name, w.Name)
+				w.Slot = "Weapon2"
 				gs.Player.Equipments.Weapon2 = &w
-				//CS更新
 				break
 			} else {
 				fmt.Printf("%sを諦めた\n", w.Name)
-				break
+				return
 			}
 
 		}
 	}
+	if e.Currentweapon == 0 {
+		gs.EquipWeapon(w.Name)
+	}
+}
+
+// Use は持っている武器を構える
+func (w Weapon) Use(gs *GameState) {
+	if err := gs.EquipWeapon(w.Name); err != nil {
+		fmt.Printf("%sを持っていません\n", w.Name)
+	}
+}
+
+// Drop は武器を捨てる。構えていた武器なら無装備になる
+func (w Weapon) Drop(gs *GameState) {
+	e := gs.Player.Equipments
+	if e.Weapon1 != nil && e.Weapon1.Name == w.Name {
+		e.Weapon1 = nil
+		if e.Currentweapon == 1 {
+			e.Currentweapon = 0
+		}
+	} else if e.Weapon2 != nil && e.Weapon2.Name == w.Name {
+		e.Weapon2 = nil
+		if e.Currentweapon == 2 {
+			e.Currentweapon = 0
+		}
+	} else {
+		fmt.Printf("%sを持っていません\n", w.Name)
+		return
+	}
+	fmt.Printf("%sを捨てた\n", w.Name)
 }
 
 type Armor struct {
@@ -293,7 +430,140 @@ type Armor struct {
 }
 
 type Item struct {
-	Name   string
-	Slot   string //Backpack Porch?
-	Effect string
+	Name     string
+	Slot     string //入れ物の名前　Backpack Porch?
+	Category string //weapon armor meal potion special など
+	Effect   string
+	Quantity int    //個数　0なら1個として扱う
+	Doses    int    //残り使用回数　ポーションなどに使用
+	Reusable bool   //trueなら使っても無くならない
+	Kind     string //武器の種類　Weaponskillの判定に使用（空なら名前と同じ）
+	CSBonus  int    //武器の戦闘力ボーナス
+	Damage   string //武器のダメージのダイス "1d8"（d20系）
+	HPBonus  int    //防具の生命力ボーナス（防具は Slot に Head か Body を書く）
+}
+
+// armorSlot は防具の装備箇所を返す（Head Body 以外はnil）
+func (e *Equipment) armorSlot(slot string) **Armor {
+	switch slot {
+	case "Head":
+		return &e.Head
+	case "Body":
+		return &e.Body
+	}
+	return nil
+}
+
+// NewArmor は本のアイテム定義から装備箇所と生命力ボーナスを読んで防具を作る
+func (gs *GameState) NewArmor(name string) Armor {
+	armor := Armor{Name: name}
+	if def, ok := gs.Items[name]; ok {
+		armor.Slot = def.Slot
+		armor.HPBonus = def.HPBonus
+	}
+	return armor
+}
+
+// applyHPBonus は防具の生命力ボーナスを現在値と最大値に反映する
+func (p *Player) applyHPBonus(bonus int) {
+	p.Stats["HP"] += bonus
+	if _, ok := p.Stats["MXHP"]; ok {
+		p.Stats["MXHP"] += bonus
+	}
+}
+
+// Get は防具を装備箇所に装備する。既に装備していれば取り替えるか尋ねる
+func (a Armor) Get(gs *GameState) {
+	slot := gs.Player.Equipments.armorSlot(a.Slot)
+	if slot == nil {
+		fmt.Printf("%sは装備できません\n", a.Name)
+		return
+	}
+	if *slot != nil {
+		if !AskYesNo(gs, fmt.Sprintf("既に%sを装備しています。%sに取り替えますか？", (*slot).Name, a.Name)) {
+			fmt.Printf("%sを諦めた\n", a.Name)
+			return
+		}
+		(*slot).Drop(gs)
+	}
+	*slot = &a
+	gs.Player.applyHPBonus(a.HPBonus)
+	fmt.Printf("%sを装備した\n", a.Name)
 }
+
+// Use は防具を装備する
+func (a Armor) Use(gs *GameState) {
+	slot := gs.Player.Equipments.armorSlot(a.Slot)
+	if slot != nil && *slot != nil && (*slot).Name == a.Name {
+		fmt.Printf("%sは既に装備しています\n", a.Name)
+		return
+	}
+	a.Get(gs)
+}
+
+// Drop は防具を外して捨て、生命力ボーナスを取り消す
+func (a Armor) Drop(gs *GameState) {
+	slot := gs.Player.Equipments.armorSlot(a.Slot)
+	if slot == nil || *slot == nil || (*slot).Name != a.Name {
+		fmt.Printf("%sを装備していません\n", a.Name)
+		return
+	}
+	gs.Player.applyHPBonus(-(*slot).HPBonus)
+	*slot = nil
+	fmt.Printf("%sを捨てた\n", a.Name)
+}
+
+// Get はアイテムを Slot で指定された入れ物に入れる
+func (i Item) Get(gs *GameState) {
+	item := i
+	gs.GetItem(&item)
+}
+
+// Use はアイテムの効果を適用し、1回分消費する
+func (i Item) Use(gs *GameState) {
+	item := gs.Player.FindItem(i.Name)
+	if item == nil {
+		fmt.Printf("%sを持っていません\n", i.Name)
+		return
+	}
+	if item.Effect == "" {
+		fmt.Printf("%sは使えません\n", item.Name)
+		return
+	}
+	if err := gs.UseItem(item); err != nil {
+		fmt.Println("エラー:", err)
+		return
+	}
+	fmt.Printf("%sを使った（%s）\n", item.Name, item.Effect)
+}
+
+// Drop はアイテムを1個捨てる
+func (i Item) Drop(gs *GameState) {
+	if gs.Player.RemoveItem(i.Name, 1) == 0 {
+		fmt.Printf("%sを持っていません\n", i.Name)
+		return
+	}
+	fmt.Printf("%sを捨てた\n", i.Name)
+}
+
+// AskYesNo は Y/N の質問をして結果を返す
+func AskYesNo(gs *GameState, question string) bool {
+	for {
+		input := strings.ToUpper(gs.ReadLine(fmt.Sprintf("%s(Y/N)\n", question)))
+
+		if input == "Y" {
+			return true
+		} else if input == "N" {
+			return false
+		} else {
+			fmt.Println("Y または N を入力してください。")
+		}
+	}
+}
+
+// インターフェースの実装を明示
+var (
+	_ Inventory = Weapon{}
+	_ Inventory = Armor{}
+	_ Inventory = Item{}
+)
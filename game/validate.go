@@ -0,0 +1,69 @@
+package game
+
+import (
+	"fmt"
+)
+
+// Validate は本のデータを検査し、見つかった問題を返す
+func Validate(config *GameConfig) []error {
+	var errs []error
+
+	switch config.LockedChoices {
+	case "", LockedChoicesShow, LockedChoicesHide:
+	default:
+		errs = append(errs, fmt.Errorf("locked_choices must be %q or %q, got %q",
+			LockedChoicesShow, LockedChoicesHide, config.LockedChoices))
+	}
+
+	for _, item := range config.Items {
+		if _, err := ParseEffects(item.Effect); err != nil {
+			errs = append(errs, fmt.Errorf("item %s: %w", item.Name, err))
+		}
+		if item.Category == CategoryArmor && item.Slot != "Head" && item.Slot != "Body" {
+			errs = append(errs, fmt.Errorf("item %s: armor needs slot \"Head\" or \"Body\"", item.Name))
+		}
+	}
+
+	ids := make(map[string]bool)
+	for _, node := range config.Nodes {
+		if ids[node.ID] {
+			errs = append(errs, fmt.Errorf("node %s: duplicate id", node.ID))
+		}
+		ids[node.ID] = true
+	}
+
+	for _, node := range config.Nodes {
+		for _, src := range node.Effects {
+			if _, err := ParseEffects(src); err != nil {
+				errs = append(errs, fmt.Errorf("node %s: %w", node.ID, err))
+			}
+		}
+		if node.NextNodeID != "" && !ids[node.NextNodeID] {
+			errs = append(errs, fmt.Errorf("node %s: next_node_id leads to unknown node %q", node.ID, node.NextNodeID))
+		}
+		if node.Type == "shop" && node.NextNodeID == "" {
+			errs = append(errs, fmt.Errorf("node %s: shop needs next_node_id", node.ID))
+		}
+		if len(node.Loot) > 0 && node.Type != "story" {
+			errs = append(errs, fmt.Errorf("node %s: loot is only offered in story nodes", node.ID))
+		}
+		for _, entry := range node.Shop {
+			if entry.Price < 0 || entry.SellPrice < 0 || entry.Stock < 0 {
+				errs = append(errs, fmt.Errorf("node %s: shop item %s has a negative price or stock", node.ID, entry.Name))
+			}
+		}
+		for _, choice := range node.Choices {
+			if !ids[choice.NextNodeID] {
+				errs = append(errs, fmt.Errorf("node %s: choice %q leads to unknown node %q",
+					node.ID, choice.Description, choice.NextNodeID))
+			}
+		}
+		for _, outcome := range node.Outcomes {
+			if !ids[outcome.NextNodeID] {
+				errs = append(errs, fmt.Errorf("node %s: outcome %q leads to unknown node %q",
+					node.ID, outcome.Condition, outcome.NextNodeID))
+			}
+		}
+	}
+	return errs
+}
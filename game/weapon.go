@@ -0,0 +1,126 @@
+package game
+
+import (
+	"fmt"
+	"strings"
+)
+
+// WeaponRules は戦闘中の武器の持ち替えを許す GameSystem が実装する
+// 実装していなければ戦闘中は持ち替えられない
+type WeaponRules interface {
+	CanSwapInCombat(gs *GameState) bool
+}
+
+// weaponSlot は武器の枠を返す（1 か 2 以外は nil）
+func (e *Equipment) weaponSlot(n int) **Weapon {
+	switch n {
+	case 1:
+		return &e.Weapon1
+	case 2:
+		return &e.Weapon2
+	}
+	return nil
+}
+
+// CurrentWeapon は構えている武器を返す（素手なら nil）
+func (e *Equipment) CurrentWeapon() *Weapon {
+	if slot := e.weaponSlot(e.Currentweapon); slot != nil {
+		return *slot
+	}
+	return nil
+}
+
+// SpareWeapon は持っているが構えていない武器を返す
+func (e *Equipment) SpareWeapon() *Weapon {
+	for n := 1; n <= 2; n++ {
+		if weapon := *e.weaponSlot(n); weapon != nil && n != e.Currentweapon {
+			return weapon
+		}
+	}
+	return nil
+}
+
+// NewWeapon は本で定義された武器を返す（定義がなければ名前だけの武器。種類は名前と同じ）
+func (gs *GameState) NewWeapon(name string) Weapon {
+	weapon := Weapon{Kind: name, Name: name}
+	if def, ok := gs.Items[name]; ok {
+		weapon.CSBonus = def.CSBonus
+		weapon.Damage = def.Damage
+		if def.Kind != "" {
+			weapon.Kind = def.Kind
+		}
+	}
+	return weapon
+}
+
+// CanChangeWeapon は今武器を持ち替えられるか確認する
+func (gs *GameState) CanChangeWeapon() error {
+	if !gs.InCombat {
+		return nil
+	}
+	if rules, ok := gs.System.(WeaponRules); ok && rules.CanSwapInCombat(gs) {
+		return nil
+	}
+	return fmt.Errorf("weapons cannot be changed during this combat")
+}
+
+// EquipWeapon は持っている武器を名前で選んで構える
+func (gs *GameState) EquipWeapon(name string) error {
+	e := gs.Player.Equipments
+	for n := 1; n <= 2; n++ {
+		if weapon := *e.weaponSlot(n); weapon != nil && strings.EqualFold(weapon.Name, name) {
+			e.Currentweapon = n
+			fmt.Printf("%sを構えた\n", weapon.Name)
+			return nil
+		}
+	}
+	return fmt.Errorf("you do not have %s", name)
+}
+
+// UnequipWeapon は構えている武器をしまって素手になる
+func (gs *GameState) UnequipWeapon() error {
+	weapon := gs.Player.Equipments.CurrentWeapon()
+	if weapon == nil {
+		return fmt.Errorf("no weapon is equipped")
+	}
+	gs.Player.Equipments.Currentweapon = 0
+	fmt.Printf("%sをしまった\n", weapon.Name)
+	return nil
+}
+
+// SwapWeapon は構えている武器をもう一方の武器に持ち替える
+func (gs *GameState) SwapWeapon() error {
+	spare := gs.Player.Equipments.SpareWeapon()
+	if spare == nil {
+		return fmt.Errorf("there is no other weapon to swap to")
+	}
+	return gs.EquipWeapon(spare.Name)
+}
+
+// displayWeapons は武器の枠と構えている武器を表示する
+func (gs *GameState) displayWeapons() {
+	fmt.Println("武器")
+	e := gs.Player.Equipments
+	if e.Weapon1 == nil && e.Weapon2 == nil {
+		fmt.Println("  装備品がありません。")
+		return
+	}
+	for n := 1; n <= 2; n++ {
+		weapon := *e.weaponSlot(n)
+		if weapon == nil {
+			continue
+		}
+		label := "予備"
+		if n == e.Currentweapon {
+			label = "装備"
+		}
+		if weapon.CSBonus != 0 {
+			fmt.Printf("%s：　%s（CS%+d）\n", label, weapon.Name, weapon.CSBonus)
+		} else {
+			fmt.Printf("%s：　%s\n", label, weapon.Name)
+		}
+	}
+	if e.Currentweapon == 0 {
+		fmt.Println("（素手）")
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"math/rand"
 	"new-gamebook/game"
+	"path/filepath"
 	"strconv"
 	"strings"
 	"time"
@@ -32,12 +33,33 @@ type CRTData struct {
 	} `toml:"results"`
 }
 
+// CRTFileName は設定ディレクトリに置く戦闘結果テーブルのファイル名
+const CRTFileName = "combat_result_table.toml"
+
+func init() {
+	game.RegisterSystem(game.SystemInfo{
+		Name:        "lonewolf",
+		Description: "Lone Wolf（戦闘力の差と乱数表で戦う）",
+		ConfigFiles: []string{CRTFileName},
+		New: func(configDir string) (game.GameSystem, error) {
+			lw := NewLoneWolfSystem(filepath.Join(configDir, CRTFileName))
+			lw.ConfigDir = configDir
+			return lw, nil
+		},
+	})
+}
+
+// roundDelay は戦闘のラウンドごとの演出の待ち時間
+var roundDelay = time.Second
+
 // LoneWolfSystem はLone Wolfゲームブックのルールを実装
 type LoneWolfSystem struct {
-	CRT       map[KeyPair]DamagePair
-	Rand      *rand.Rand
-	CRTFile   string
-	ConfigDir string
+	CRT         map[KeyPair]DamagePair
+	Rand        *rand.Rand
+	CRTFile     string
+	ConfigDir   string
+	CombatLog   []RoundEvent // 直前の遭遇戦のラウンド記録
+	Weaponskill string       // Weaponskill の技能で得意な武器の種類（player.weaponskill）
 }
 
 // NewLoneWolfSystem は新しいLoneWolfSystemインスタンスを生成
@@ -51,7 +73,12 @@ func NewLoneWolfSystem(crtFile string) *LoneWolfSystem {
 }
 
 // インターフェースの実装を明示
-var _ game.GameSystem = (*LoneWolfSystem)(nil)
+var (
+	_ game.GameSystem     = (*LoneWolfSystem)(nil)
+	_ game.WeaponRules    = (*LoneWolfSystem)(nil)
+	_ game.ContainerRules = (*LoneWolfSystem)(nil)
+	_ game.DeathRules     = (*LoneWolfSystem)(nil)
+)
 
 // Initialize はLoneWolfSystemを初期化
 func (lw *LoneWolfSystem) Initialize(config *game.GameConfig) error {
@@ -60,6 +87,10 @@ func (lw *LoneWolfSystem) Initialize(config *game.GameConfig) error {
 		return fmt.Errorf("missing HP or CS in player stats")
 	}
 
+	if kind, ok := config.Player["weaponskill"].(string); ok {
+		lw.Weaponskill = kind
+	}
+
 	var data CRTData
 	if _, err := toml.DecodeFile(lw.CRTFile, &data); err != nil {
 		return fmt.Errorf("error decoding CRT file: %v", err)
@@ -83,91 +114,287 @@ func normalizeCombatRatio(ratio int) int {
 	return ratio // それ以外はそのまま
 }
 
-// makeCombatResult は戦闘結果を返す
-func (lw *LoneWolfSystem) makeCombatResult(PCS int, ECS int) DamagePair {
-	source := rand.NewSource(time.Now().UnixNano())
-	r := rand.New(source)
-	randomNumber := r.Intn(10)
+// RoundEvent は1ラウンド分の戦闘結果表の判定を記録
+type RoundEvent struct {
+	Round        int
+	RandNum      int
+	CombatRatio  int // 正規化前の戦闘比率
+	Result       DamagePair
+	EnemyKilled  bool // 戦闘結果表の K で敵が即死
+	PlayerKilled bool // 戦闘結果表の K でプレイヤーが即死
+}
+
+// String はラウンドの判定内容を表示用に整形
+func (e RoundEvent) String() string {
+	return fmt.Sprintf("ラウンド%d: 乱数%d 戦闘比率%+d → 敵-%d / あなた-%d",
+		e.Round, e.RandNum, e.CombatRatio, e.Result.EnemyLoss, e.Result.PlayerLoss)
+}
+
+// makeCombatResult は1回の乱数で両者の戦闘結果を返す
+func (lw *LoneWolfSystem) makeCombatResult(PCS int, ECS int) RoundEvent {
+	return lw.lookupCombatResult(lw.Random(), PCS, ECS)
+}
+
+// lookupCombatResult は乱数と戦闘力から戦闘結果表を引く
+func (lw *LoneWolfSystem) lookupCombatResult(randomNumber, PCS, ECS int) RoundEvent {
 	CombatRatio := PCS - ECS // 例えば、+5 の戦闘比率だったとする
 	normalizedCR := normalizeCombatRatio(CombatRatio)
 	key := KeyPair{RandNum: randomNumber, ComRatio: normalizedCR}
+	event := RoundEvent{RandNum: randomNumber, CombatRatio: CombatRatio}
 	result, ok := lw.CRT[key]
-	if ok {
-		return result
-	} else {
+	if !ok {
 		fmt.Println("Key not found in the map.")
-		return DamagePair{
-			EnemyLoss:  0,
-			PlayerLoss: 0,
-			IsKilled:   false,
-		}
+		return event
+	}
+	event.Result = result
+	if result.IsKilled {
+		// K は戦闘比率が不利ならプレイヤー、有利なら敵の即死を表す
+		event.PlayerKilled = normalizedCR < 0
+		event.EnemyKilled = normalizedCR > 0
 	}
+	return event
 }
 
 // handleEncounterNode は遭遇戦ノードの処理 (簡易版)
 func (lw *LoneWolfSystem) Encounter(gs *game.GameState, node game.Node) error {
+	node.Enemies = game.CopyEnemies(node.Enemies) // 戻ってきた時は敵も元の状態から
 	fmt.Println("\n--- エンカウント！ ---")
+	gs.InCombat = true
+	defer func() {
+		gs.InCombat = false
+		gs.Player.ExpireEffects(game.DurationCombat)
+	}()
+
+	// エンカウント情報を表示
+	if node.Unarmed {
+		fmt.Println("素手で戦わなければならない！（戦闘力-4）")
+	} else if gs.Player.Equipments.CurrentWeapon() == nil {
+		fmt.Println("武器を構えていない！（戦闘力-4）")
+	}
+	for _, enemy := range node.Enemies {
+		if enemy.MindblastImmune && hasDiscipline(gs, "Mindblast") {
+			fmt.Printf("%sにはマインドブラストが効かない！\n", enemy.Name)
+		}
+	}
+	simultaneous := node.CombatMode == game.CombatSimultaneous
+	if simultaneous {
+		fmt.Println("敵が一斉に襲いかかってくる！")
+	}
+	if node.NoEscape {
+		fmt.Println("この戦いからは逃げられない！")
+	}
 
-	for _, currentEnemy := range node.Enemies {
-		// エンカウント情報が完全かチェックし、敵を設定
+	lw.CombatLog = nil
+	result := "combat_won"
+	round := 0 // 遭遇戦全体のラウンド数
+	var currentEnemy *game.Enemy
+	for {
+		living := game.LivingEnemies(node.Enemies)
+		if len(living) == 0 {
+			break // 全ての敵を倒した
+		}
+		if node.RoundLimit > 0 && round >= node.RoundLimit {
+			fmt.Printf("\n%dラウンドが経過した。\n", round)
+			result = "round_limit"
+			break
+		}
+		round++
+		gs.CombatRounds = round
+
+		// 攻撃する相手を決める
+		if simultaneous {
+			currentEnemy = game.ChooseTarget(gs, living)
+		} else if currentEnemy != living[0] {
+			currentEnemy = living[0]
+			fmt.Printf("\n%sとの戦い！\n", currentEnemy.Name)
+		}
 
-		for {
-			fmt.Printf("\nLone Wolf (HP:%d CS;%d)",
-				gs.Player.Stats["HP"], gs.Player.Stats["CS"])
-			fmt.Printf("\n%s (HP:%d CS:%d)\n",
-				currentEnemy.Name, currentEnemy.HP, currentEnemy.CS) // 敵のHPを更新して表示
+		playerCS := lw.combatSkill(gs, node, currentEnemy, round)
+		fmt.Printf("\n[ラウンド%d]", round)
+		fmt.Printf("\nLone Wolf (HP:%d CS;%d)",
+			gs.Player.Stats["HP"], playerCS)
+		fmt.Printf("\n%s (HP:%d CS:%d)\n",
+			currentEnemy.Name, currentEnemy.HP, currentEnemy.CS) // 敵のHPを更新して表示
+
+		if evade, ok := game.EvadeOutcome(node, round); ok && game.AskEvade(gs, evade) {
+			// 逃走するラウンドはプレイヤーのみダメージを受ける
+			event := lw.makeCombatResult(playerCS, currentEnemy.CS)
+			event.Round = round
+			lw.CombatLog = append(lw.CombatLog, event)
+			fmt.Println(event)
+			gs.Player.Stats["HP"] -= event.Result.PlayerLoss
+			fmt.Printf("逃げる途中で%dダメージを受けた！\n", event.Result.PlayerLoss)
+			if event.PlayerKilled {
+				gs.Player.Stats["HP"] = 0
+			}
+			if gs.Player.Stats["HP"] <= 0 {
+				fmt.Println("あなたは倒れた！")
+				result = "combat_lost"
+				break
+			}
+			fmt.Println("戦いから逃げ切った！")
+			gs.CurrentNodeID = evade.NextNodeID
+			return nil
+		}
+		playerCS = lw.combatSkill(gs, node, currentEnemy, round) // 入力中に武器を持ち替えたかもしれない
 
-			time.Sleep(1 * time.Second)
-			fmt.Println("\n力を込めて物理で殴る！")
-			time.Sleep(2 * time.Second)
+		time.Sleep(roundDelay)
+		fmt.Println("\n力を込めて物理で殴る！")
+		time.Sleep(2 * roundDelay)
 
-			Edamage := lw.makeCombatResult(gs.Player.Stats["CS"], currentEnemy.CS).EnemyLoss
-			Pdamage := lw.makeCombatResult(gs.Player.Stats["CS"], currentEnemy.CS).PlayerLoss
-			currentEnemy.HP -= Edamage
-			gs.Player.Stats["HP"] -= Pdamage
-			fmt.Printf("あなたは%sに%dダメージを与えた！\nそしてあなたは%dダメージを受けた！\n",
-				currentEnemy.Name, Edamage, Pdamage)
+		event := lw.makeCombatResult(playerCS, currentEnemy.CS)
+		event.Round = round
+		lw.CombatLog = append(lw.CombatLog, event)
+		fmt.Println(event)
 
-			// 敵のHPチェック
-			if currentEnemy.HP <= 0 {
-				fmt.Printf("%sを倒した！\n", currentEnemy.Name)
-				// 勝利した場合の次のノードを探す
+		Edamage := event.Result.EnemyLoss
+		Pdamage := event.Result.PlayerLoss
+		if currentEnemy.Undead && wieldsWeapon(gs, "Sommerswerd") {
+			Edamage *= 2 // アンデッドにはソマースウェルドのダメージが2倍
+		}
+		currentEnemy.HP -= Edamage
+		gs.Player.Stats["HP"] -= Pdamage
+		fmt.Printf("あなたは%sに%dダメージを与えた！\nそしてあなたは%dダメージを受けた！\n",
+			currentEnemy.Name, Edamage, Pdamage)
+		if event.EnemyKilled {
+			fmt.Printf("会心の一撃！%sは即死した！\n", currentEnemy.Name)
+			currentEnemy.HP = 0
+		}
+		if event.PlayerKilled {
+			fmt.Println("致命の一撃を受けた！")
+			gs.Player.Stats["HP"] = 0
+		}
 
-				break // 戦闘ループを終了し次の敵がいれば次の敵へ
+		// 同時戦闘では狙わなかった敵も攻撃してくる（プレイヤーの損失のみ適用）
+		attackers := []*game.Enemy{currentEnemy}
+		if simultaneous {
+			for _, other := range living {
+				if other == currentEnemy {
+					continue
+				}
+				attackers = append(attackers, other)
+				otherEvent := lw.makeCombatResult(lw.combatSkill(gs, node, other, round), other.CS)
+				otherEvent.Round = round
+				lw.CombatLog = append(lw.CombatLog, otherEvent)
+				gs.Player.Stats["HP"] -= otherEvent.Result.PlayerLoss
+				fmt.Printf("%sの攻撃で%dダメージを受けた！\n", other.Name, otherEvent.Result.PlayerLoss)
+				if otherEvent.PlayerKilled {
+					fmt.Println("致命の一撃を受けた！")
+					gs.Player.Stats["HP"] = 0
+				}
 			}
+		}
 
-			if gs.Player.Stats["HP"] <= 0 {
-				break // プレイヤーのHPが0以下になった場合、抜ける
+		for _, attacker := range attackers {
+			if attacker.Mindforce > 0 && !hasDiscipline(gs, "Mindshield") {
+				gs.Player.Stats["HP"] -= attacker.Mindforce
+				fmt.Printf("%sのマインドフォースで%dダメージを受けた！\n",
+					attacker.Name, attacker.Mindforce)
 			}
+		}
 
+		// 敵のHPチェック
+		if currentEnemy.HP <= 0 {
+			fmt.Printf("%sを倒した！\n", currentEnemy.Name)
 		}
 
 		if gs.Player.Stats["HP"] <= 0 {
 			fmt.Println("あなたは倒れた！")
-			gs.CurrentNodeID = "game_over"
-			break // プレイヤーのHPが0以下になった場合、ゲームオーバーへ
+			result = "combat_lost"
+			break // プレイヤーのHPが0以下になった場合、戦闘終了
 		}
 	}
-	foundOutcome := false
-	for _, outcome := range node.Outcomes {
-		if outcome.Condition == "combat_won" { // "combat_won" 条件をチェック
-			gs.CurrentNodeID = outcome.NextNodeID
-			foundOutcome = true
-			break
+
+	outcome, ok := game.SelectOutcome(gs, node, result)
+	if !ok {
+		if result != "combat_lost" {
+			fmt.Println("エラー: 戦闘後の次のノードが見つかりません。ゲーム終了。")
 		}
-	}
-	if !foundOutcome {
-		fmt.Println("エラー: 勝利時の次のノードが見つかりません。ゲーム終了。")
 		gs.CurrentNodeID = "game_over"
+		return nil
 	}
+	gs.CurrentNodeID = outcome.NextNodeID
 	return nil
 }
 
+// combatSkill はノードと敵の条件を反映した戦闘力を返す
+func (lw *LoneWolfSystem) combatSkill(gs *game.GameState, node game.Node, enemy *game.Enemy, round int) int {
+	cs := gs.Player.Stats["CS"]
+	if hasDiscipline(gs, "Mindblast") && !enemy.MindblastImmune {
+		cs += 2
+	}
+	cs += lw.weaponBonus(gs, node)
+	for _, modifier := range node.CombatModifiers {
+		if modifierApplies(gs, modifier, round) {
+			cs += modifier.Value
+		}
+	}
+	return cs
+}
+
+// modifierApplies は戦闘力修正が現在のラウンドで有効か判定
+func modifierApplies(gs *game.GameState, m game.CombatModifier, round int) bool {
+	if m.FromRound > 0 && round < m.FromRound {
+		return false
+	}
+	if m.ToRound > 0 && round > m.ToRound {
+		return false
+	}
+	if m.IfDiscipline != "" && !hasDiscipline(gs, m.IfDiscipline) {
+		return false
+	}
+	if m.UnlessDiscipline != "" && hasDiscipline(gs, m.UnlessDiscipline) {
+		return false
+	}
+	if m.IfItem != "" && !gs.Player.HasItem(m.IfItem) {
+		return false
+	}
+	if m.UnlessItem != "" && gs.Player.HasItem(m.UnlessItem) {
+		return false
+	}
+	return true
+}
+
+// hasDiscipline はカイの技能を持っているか確認
+func hasDiscipline(gs *game.GameState, name string) bool {
+	return gs.Player.HasAttribute(name)
+}
+
+// weaponBonus は構えている武器による戦闘力の修正を返す
+// 素手なら-4、武器の CSBonus に加えて Weaponskill の得意な武器なら+2
+func (lw *LoneWolfSystem) weaponBonus(gs *game.GameState, node game.Node) int {
+	weapon := gs.Player.Equipments.CurrentWeapon()
+	if node.Unarmed || weapon == nil {
+		return -4
+	}
+	bonus := weapon.CSBonus
+	if hasDiscipline(gs, "Weaponskill") && lw.Weaponskill != "" && strings.EqualFold(weapon.Kind, lw.Weaponskill) {
+		bonus += 2
+	}
+	return bonus
+}
+
+// CanSwapInCombat は戦闘中の武器の持ち替えを許す（入力を求められた時に swap で持ち替える）
+func (lw *LoneWolfSystem) CanSwapInCombat(gs *game.GameState) bool {
+	return true
+}
+
+// wieldsWeapon は指定した武器を装備中か確認
+func wieldsWeapon(gs *game.GameState, name string) bool {
+	weapon := gs.Player.Equipments.CurrentWeapon()
+	return weapon != nil && weapon.Name == name
+}
+
 // UpdatePlayer はプレイヤーの状態を更新
 func (lw *LoneWolfSystem) UpdatePlayer(gs *game.GameState, action string) error {
-	if action == "heal" && gs.Player.Attributes["Healing"] {
-		gs.Player.Stats["HP"] += 1
-		fmt.Println("Healing Discipline restored 1 HP!")
+	switch action {
+	case "heal":
+		if gs.Player.Attributes["Healing"] {
+			gs.Player.Stats["HP"] += 1
+			fmt.Println("Healing Discipline restored 1 HP!")
+		}
+	case "eat_meal":
+		fmt.Println("食事は本文で指示された時にとります。")
 	}
 	return nil
 }
@@ -176,10 +403,7 @@ func (lw *LoneWolfSystem) MakingPlayer(gs *game.GameState) error {
 	fmt.Println("キャラクターメイキング")
 	for {
 		randomNumCS := lw.Rand.Intn(10)
-		fmt.Printf("戦闘力！\n運命の数は%d\n受け入れますか？(Y/N)\n", randomNumCS)
-		input, _ := gs.Reader.ReadString('\n')
-		input = strings.TrimSpace(input)
-		input = strings.ToUpper(input)
+		input := strings.ToUpper(gs.ReadLine(fmt.Sprintf("戦闘力！\n運命の数は%d\n受け入れますか？(Y/N)\n", randomNumCS)))
 
 		if input == "Y" {
 			gs.Player.Stats["CS"] = 10 + randomNumCS
@@ -196,13 +420,11 @@ func (lw *LoneWolfSystem) MakingPlayer(gs *game.GameState) error {
 
 	for {
 		randomNumHP := lw.Rand.Intn(10)
-		fmt.Printf("生命力！\n運命の数は%d\n受け入れますか？(Y/N)\n", randomNumHP)
-		input, _ := gs.Reader.ReadString('\n')
-		input = strings.TrimSpace(input)
-		input = strings.ToUpper(input)
+		input := strings.ToUpper(gs.ReadLine(fmt.Sprintf("生命力！\n運命の数は%d\n受け入れますか？(Y/N)\n", randomNumHP)))
 
 		if input == "Y" {
 			gs.Player.Stats["HP"] = 10 + randomNumHP
+			gs.Player.Stats["MXHP"] = gs.Player.Stats["HP"] // 回復の上限
 			fmt.Printf("お前の生命力は%dと定まった！\n", gs.Player.Stats["HP"])
 			break
 
@@ -217,14 +439,11 @@ func (lw *LoneWolfSystem) MakingPlayer(gs *game.GameState) error {
 
 	for {
 		randomNumGOLD := lw.Rand.Intn(10)
-		fmt.Printf("所持金！\n運命の数は%d\n受け入れますか？(Y/N)\n", randomNumGOLD)
-		input, _ := gs.Reader.ReadString('\n')
-		input = strings.TrimSpace(input)
-		input = strings.ToUpper(input)
+		input := strings.ToUpper(gs.ReadLine(fmt.Sprintf("所持金！\n運命の数は%d\n受け入れますか？(Y/N)\n", randomNumGOLD)))
 
 		if input == "Y" {
-			gs.Player.Stats["HP"] = 10 + randomNumGOLD
-			fmt.Printf("お前の生命力は%dと定まった！\n", gs.Player.Stats["HP"])
+			gs.Player.AddGold(randomNumGOLD)
+			fmt.Printf("お前の所持金は%dゴールドと定まった！\n", gs.Player.Gold)
 			break
 
 		} else if input == "N" {
@@ -238,6 +457,27 @@ func (lw *LoneWolfSystem) MakingPlayer(gs *game.GameState) error {
 	return nil
 }
 
+// Lone Wolf の持ち物の上限
+const (
+	backpackCapacity = 8  // バックパックに入るアイテムの数
+	maxGoldCrowns    = 50 // ベルトポーチに入る金貨の数
+)
+
+// SetupContainers はバックパックと特別なアイテムの上限を Lone Wolf のルールに合わせる
+func (lw *LoneWolfSystem) SetupContainers(p *game.Player) {
+	backpack := p.Container(game.SlotBackpack)
+	backpack.Capacity = backpackCapacity
+	if p.Container(game.SlotSpecial) == nil {
+		p.Equipments.Containers[game.SlotSpecial] = game.NewContainer(game.SlotSpecial, 0)
+	}
+	p.MaxGold = maxGoldCrowns
+}
+
+// IsDead は生命力が0以下か確認
+func (lw *LoneWolfSystem) IsDead(p *game.Player) bool {
+	return p.Stats["HP"] <= 0
+}
+
 // Random は戦闘表用の乱数を生成（0-9）
 func (lw *LoneWolfSystem) Random() int {
 	return lw.Rand.Intn(10)
@@ -245,6 +485,18 @@ func (lw *LoneWolfSystem) Random() int {
 
 func (lw *LoneWolfSystem) HandleNode(gs *game.GameState, node game.Node) error {
 	lw.UpdatePlayer(gs, "heal")
+	if node.LoseBackpack {
+		lost := gs.Player.LoseContainer(game.SlotBackpack)
+		fmt.Printf("\nバックパックを失った！（%d個のアイテムが失われた）\n", len(lost))
+	}
+	if node.EatMeal {
+		lw.eatMeal(gs, node)
+		if gs.Player.Stats["HP"] <= 0 {
+			fmt.Println("あなたは飢えで倒れた！")
+			gs.CurrentNodeID = "game_over"
+			return nil
+		}
+	}
 	switch node.Type {
 	case "story":
 		fmt.Printf("Story: %s\n", node.Text)
@@ -257,12 +509,37 @@ func (lw *LoneWolfSystem) HandleNode(gs *game.GameState, node game.Node) error {
 		return fmt.Errorf("no enemy defined for combat node")
 	case "random_roll":
 		return handleRandomNode(gs, node)
+	case "shop":
+		fmt.Printf("Story: %s\n", node.Text)
+		return game.HandleShopNode(gs, node)
+	case "end":
+		fmt.Printf("Story: %s\n", node.Text)
+		return nil
 
 	default:
 		return fmt.Errorf("unknown node type: %s", node.Type)
 	}
 }
 
+// mealPenalty は食事ができなかった時に失う生命力
+const mealPenalty = 3
+
+// eatMeal は食事の指示を処理する。狩猟の技能があれば免除、食料がなければ生命力を失う
+func (lw *LoneWolfSystem) eatMeal(gs *game.GameState, node game.Node) {
+	fmt.Println("\n食事の時間だ。")
+	if hasDiscipline(gs, "Hunting") && !node.NoHunting {
+		fmt.Println("狩猟の技能で食べ物を手に入れた。食料は減らない。")
+		return
+	}
+	if meal := gs.Player.FindItem("Meal"); meal != nil {
+		gs.Player.ConsumeItem(meal)
+		fmt.Printf("食料を1つ食べた。（残り%d食）\n", gs.Player.CountItem("Meal"))
+		return
+	}
+	gs.Player.Stats["HP"] -= mealPenalty
+	fmt.Printf("食料がない！生命力が%d減った。（HP:%d）\n", mealPenalty, gs.Player.Stats["HP"])
+}
+
 func handleRandomNode(gs *game.GameState, node game.Node) error {
 
 	source := rand.NewSource(time.Now().UnixNano())
@@ -278,19 +555,25 @@ func handleRandomNode(gs *game.GameState, node game.Node) error {
 	}
 
 	for {
-		fmt.Print("選択してください (番号): ")
-		input, _ := gs.Reader.ReadString('\n')
-		input = strings.TrimSpace(input)
+		input, err := gs.ReadChoice("選択してください (番号): ")
+		if err != nil {
+			return err
+		}
 		choiceNum, err := strconv.Atoi(input)
+		if err != nil || choiceNum < 1 || choiceNum > len(node.Outcomes) {
+			fmt.Printf("1〜%dの番号を入力してください。\n", len(node.Outcomes))
+			continue
+		}
 
 		outcome := node.Outcomes[choiceNum-1]
 
-		if err == nil &&
-			contains_int(outcome.ConditionInt, randomNumber) {
+		if !contains_int(outcome.ConditionInt, randomNumber) {
+			fmt.Println("条件を満たしていません。")
+		} else if reason := game.Requirement(outcome.Expr, gs); reason != "" {
+			fmt.Printf("その選択肢は選べません（%s）\n", reason)
+		} else {
 			gs.CurrentNodeID = outcome.NextNodeID
 			break //RunLoopへ戻る
-		} else {
-			fmt.Println("条件を満たしていません。")
 		}
 	}
 	return nil
@@ -300,84 +583,31 @@ func handleRandomNode(gs *game.GameState, node game.Node) error {
 func (lw *LoneWolfSystem) handleStoryNode(gs *game.GameState, node game.Node) error {
 	if len(node.Choices) == 0 {
 		fmt.Println("このノードには選択肢がありません。ゲーム終了。")
-		gs.CurrentNodeID = "game_over" // 選択肢がなければゲームオーバーに送るか、別の処理
+		gs.CurrentNodeID = "game_over" // 選択肢がなければゲームオーバーに送る
+		return nil
+	}
 
+	if err := game.OfferLoot(gs, node); err != nil {
+		return err
 	}
 
-	fmt.Println("\n選択肢:")
-	for i, choice := range node.Choices {
-		fmt.Printf("%d. %s\n", i+1, choice.Description)
+	shown := game.ShowChoices(gs, node)
+	if len(shown) == 0 {
+		fmt.Println("選べる選択肢がありません。ゲーム終了。")
+		gs.CurrentNodeID = "game_over"
+		return nil
 	}
 
 	for {
-		fmt.Print("選択してください (番号): ")
-		input, _ := gs.Reader.ReadString('\n')
-		input = strings.TrimSpace(input)
-		choiceNum, err := strconv.Atoi(input)
-
-		if err != nil || choiceNum < 1 || choiceNum > len(node.Choices) {
-			gs.DisplayStatus()
-			continue
+		input, err := gs.ReadChoice("選択してください (番号): ")
+		if err != nil {
+			return err // load や back で移動した
 		}
-
-		choice := node.Choices[choiceNum-1]
-		//required_discipline_name := *choice.RequiredDiscipline
-		//required_item_name := *choice.RequiredItem
-
-		var required_discipline_name string
-		if choice.RequiredDiscipline != "" {
-			required_discipline_name = choice.RequiredDiscipline
-		}
-
-		var required_item_name string
-		if choice.RequiredItem != "" {
-			required_item_name = choice.RequiredItem
-		}
-
-		//fmt.Print(required_discipline_name)
-
-		var backpackcontains []string
-		for _, item := range gs.Player.Equipments.Backpack {
-			backpackcontains = append(backpackcontains, item.Name)
-		}
-		if                //err == nil && //エラーじゃなく
-		choiceNum >= 1 && //1以上で
-			choiceNum <= len(node.Choices) && //選択肢数以下で
-			choice.RequiredDiscipline == "" && //必須ディシプリンなし
-			choice.RequiredItem == "" { //必須アイテムなし
-			gs.CurrentNodeID = node.Choices[choiceNum-1].NextNodeID
-			break
-		} else if //err == nil &&
-		choiceNum >= 1 &&
-			choiceNum <= len(node.Choices) &&
-			//choice.RequiredDiscipline != nil &&
-			//choice.RequiredItem == nil &&
-			gs.Player.Attributes[required_discipline_name] {
-			gs.CurrentNodeID = node.Choices[choiceNum-1].NextNodeID
-			break
-		} else if //err == nil &&
-		choiceNum >= 1 &&
-			choiceNum <= len(node.Choices) &&
-			choice.RequiredDiscipline == "" &&
-			choice.RequiredItem != "" &&
-			contains_str(backpackcontains, required_item_name) {
-			gs.CurrentNodeID = node.Choices[choiceNum-1].NextNodeID
-			break
-		} else {
-			//fmt.Println("無効な入力です。もう一度入力してください。")
-			gs.DisplayStatus()
+		if choice, ok := game.PickChoice(gs, shown, input); ok { // 技能・アイテムなどの条件を満たしている
+			gs.CurrentNodeID = choice.NextNodeID
+			return nil
 		}
 	}
-	return nil
-}
-
-func contains_str(slice []string, str string) bool {
-	for _, s := range slice {
-		if s == str {
-			return true
-		}
-	}
-	return false
 }
 
 func contains_int(slice []int, number int) bool {
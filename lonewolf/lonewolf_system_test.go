@@ -0,0 +1,83 @@
+package lonewolf
+
+import (
+	"bufio"
+	"math/rand"
+	"strings"
+	"testing"
+
+	"new-gamebook/game"
+)
+
+// newTestSystem は戦闘結果表を読み込んだ LoneWolfSystem を返す
+func newTestSystem(t *testing.T) *LoneWolfSystem {
+	t.Helper()
+	lw := NewLoneWolfSystem("../" + CRTFileName)
+	lw.Rand = rand.New(rand.NewSource(1))
+	config := &game.GameConfig{Player: map[string]interface{}{
+		"stats": map[string]interface{}{"HP": int64(20), "CS": int64(15)},
+	}}
+	if err := lw.Initialize(config); err != nil {
+		t.Fatal(err)
+	}
+	return lw
+}
+
+func TestLostCombatEndsGame(t *testing.T) {
+	roundDelay = 0
+	lw := newTestSystem(t)
+	gs := &game.GameState{
+		Player: game.NewPlayer(),
+		System: lw,
+		Reader: bufio.NewReader(strings.NewReader("")),
+		Nodes: map[string]game.Node{
+			"2": {ID: "2", Type: "encounter",
+				Enemies: []*game.Enemy{{Name: "Giak", HP: 100, CS: 40}},
+				Outcomes: []game.Outcome{
+					{Condition: "combat_won", NextNodeID: "3"},
+					{Condition: "combat_lost", NextNodeID: "game_over"},
+				}},
+			"game_over": {ID: "game_over", Type: "end", Text: "ゲームオーバー"},
+		},
+		CurrentNodeID: "2",
+	}
+	gs.Player.Stats["HP"] = 1
+	gs.Player.Stats["CS"] = 10
+
+	if err := lw.HandleNode(gs, gs.Nodes["2"]); err != nil {
+		t.Fatalf("encounter: %v", err)
+	}
+	if gs.CurrentNodeID != "game_over" {
+		t.Fatalf("CurrentNodeID = %q, want game_over", gs.CurrentNodeID)
+	}
+	if err := lw.HandleNode(gs, gs.Nodes[gs.CurrentNodeID]); err != nil {
+		t.Errorf("end node: %v", err)
+	}
+}
+
+func TestLookupCombatResult(t *testing.T) {
+	lw := newTestSystem(t)
+	tests := []struct {
+		roll, pcs, ecs int
+		want           RoundEvent
+	}{
+		{5, 15, 15, RoundEvent{RandNum: 5, CombatRatio: 0, Result: DamagePair{EnemyLoss: 7, PlayerLoss: 2}}},
+		{0, 15, 15, RoundEvent{RandNum: 0, CombatRatio: 0, Result: DamagePair{EnemyLoss: 12}}},
+		{6, 20, 15, RoundEvent{RandNum: 6, CombatRatio: 5, Result: DamagePair{EnemyLoss: 11, PlayerLoss: 1}}},
+		{4, 10, 25, RoundEvent{RandNum: 4, CombatRatio: -15, Result: DamagePair{PlayerLoss: 8}}},
+		// K: 戦闘比率が不利ならプレイヤーが即死
+		{1, 10, 21, RoundEvent{RandNum: 1, CombatRatio: -11, Result: DamagePair{IsKilled: true}, PlayerKilled: true}},
+		{1, 10, 30, RoundEvent{RandNum: 1, CombatRatio: -20, Result: DamagePair{IsKilled: true}, PlayerKilled: true}},
+		{1, 10, 19, RoundEvent{RandNum: 1, CombatRatio: -9, Result: DamagePair{IsKilled: true}, PlayerKilled: true}},
+		{1, 10, 18, RoundEvent{RandNum: 1, CombatRatio: -8, Result: DamagePair{PlayerLoss: 8}}},
+		// K: 戦闘比率が有利なら敵が即死
+		{9, 22, 15, RoundEvent{RandNum: 9, CombatRatio: 7, Result: DamagePair{EnemyLoss: 18, IsKilled: true}, EnemyKilled: true}},
+		{0, 26, 15, RoundEvent{RandNum: 0, CombatRatio: 11, Result: DamagePair{EnemyLoss: 18, IsKilled: true}, EnemyKilled: true}},
+		{0, 40, 15, RoundEvent{RandNum: 0, CombatRatio: 25, Result: DamagePair{EnemyLoss: 18, IsKilled: true}, EnemyKilled: true}},
+	}
+	for _, tt := range tests {
+		if got := lw.lookupCombatResult(tt.roll, tt.pcs, tt.ecs); got != tt.want {
+			t.Errorf("lookupCombatResult(%d, %d, %d) = %+v, want %+v", tt.roll, tt.pcs, tt.ecs, got, tt.want)
+		}
+	}
+}
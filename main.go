@@ -2,30 +2,23 @@ package main
 
 import (
 	"bufio"
+	"flag"
 	"fmt"
 	"io/ioutil"
 	"log"
 	"os"
+	"path/filepath"
 
 	//srings"
 
-	"new-gamebook/fightingfantasy"
 	"new-gamebook/game"
-	"new-gamebook/lonewolf"
 
 	"github.com/BurntSushi/toml"
 )
 
-// NewGameSystem はシステム名に基づいて GameSystem を返す
+// NewGameSystem はシステム名に基づいて登録された GameSystem を返す
 func NewGameSystem(systemName, configDir string) (game.GameSystem, error) {
-	switch systemName {
-	case "lonewolf":
-		return lonewolf.NewLoneWolfSystem(configDir + "/combat_result_table.toml"), nil
-	case "fightingfantasy":
-		return fightingfantasy.NewFightingFantasySystem(), nil
-	default:
-		return nil, fmt.Errorf("unknown system: %s", systemName)
-	}
+	return game.NewSystem(systemName, configDir)
 }
 
 // NewGameState はゲーム状態を初期化
@@ -43,6 +36,7 @@ func NewGameState(config *game.GameConfig, configDir string) (*game.GameState, e
 		Reader:        bufio.NewReader(os.Stdin),
 		System:        system, // System を設定
 		CurrentNodeID: "1",
+		LockedChoices: config.LockedChoices,
 	}
 	// ...（Player, Nodes の初期化コード）
 
@@ -52,19 +46,15 @@ func NewGameState(config *game.GameConfig, configDir string) (*game.GameState, e
 		nodeMap[node.ID] = node
 	}
 
-	player := &lonewolf.Player{
-		Stats:      make(map[string]int),
-		Attributes: make(map[string]bool),
-		Equipments: &lonewolf.Equipment{
-			Head:          nil,
-			Body:          nil,
-			Currentweapon: 0,
-			Weapon1:       nil,
-			Weapon2:       nil,
-			Shield:        false,
-			Backpack:      []*lonewolf.Item{},
-		},
-		Gold: 0,
+	//アイテム定義　効果の記述は game.Validate で検査する
+	gs.Items = make(map[string]game.Item)
+	for _, item := range config.Items {
+		gs.Items[item.Name] = item
+	}
+
+	player := game.NewPlayer()
+	if rules, ok := system.(game.ContainerRules); ok {
+		rules.SetupContainers(player) // 持ち物を入れる前に容量を決める
 	}
 
 	if stats, ok := config.Player["stats"].(map[string]interface{}); ok {
@@ -82,23 +72,36 @@ func NewGameState(config *game.GameConfig, configDir string) (*game.GameState, e
 		}
 	}
 
-	/*
-		if inventory, ok := config.Player["inventory"].([]interface{}); ok {
-			for _, item := range inventory {
-				if str, ok := item.(string); ok {
-					player.Inventory = append(player.Inventory, str)
+	if inventory, ok := config.Player["inventory"].([]interface{}); ok {
+		for _, item := range inventory {
+			if str, ok := item.(string); ok {
+				if err := player.AddItem(gs.NewItem(str)); err != nil {
+					return nil, fmt.Errorf("failed to add %s: %w", str, err)
 				}
 			}
 		}
+	}
 
-		if equipment, ok := config.Player["equipment"].(map[string]interface{}); ok {
-			for k, weapon := range equipment {
-				if str, ok := weapon.(string); ok {
-					player.Equipment[k] = str
-				}
+	if equipment, ok := config.Player["equipment"].(map[string]interface{}); ok {
+		if str, ok := equipment["weapon1"].(string); ok && str != "" {
+			weapon := gs.NewWeapon(str)
+			weapon.Slot = "Weapon1"
+			player.Equipments.Weapon1 = &weapon
+			player.Equipments.Currentweapon = 1 // 最初の武器を構えておく
+		}
+		if str, ok := equipment["weapon2"].(string); ok && str != "" {
+			weapon := gs.NewWeapon(str)
+			weapon.Slot = "Weapon2"
+			player.Equipments.Weapon2 = &weapon
+			if player.Equipments.Currentweapon == 0 {
+				player.Equipments.Currentweapon = 2
 			}
 		}
-	*/
+	}
+
+	if gold, ok := config.Player["gold"].(int64); ok {
+		player.AddGold(int(gold)) // 所持金の上限を超えた分は捨てる
+	}
 
 	gs.Nodes = nodeMap
 	gs.Player = player
@@ -108,7 +111,18 @@ func NewGameState(config *game.GameConfig, configDir string) (*game.GameState, e
 // ...（Run メソッドは game.GameState に移動）
 
 func main() {
-	tomlData, err := ioutil.ReadFile("testlw.toml")
+	listSystems := flag.Bool("list-systems", false, "使えるルールシステムの一覧を表示する")
+	bookPath := flag.String("book", "testlw.toml", "遊ぶ本のファイル（引数で指定してもよい）")
+	flag.Parse()
+	if *listSystems {
+		game.ListSystems()
+		return
+	}
+	if flag.NArg() > 0 {
+		*bookPath = flag.Arg(0)
+	}
+
+	tomlData, err := ioutil.ReadFile(*bookPath)
 	if err != nil {
 		log.Fatalf("Error reading TOML file: %v", err)
 	}
@@ -118,7 +132,14 @@ func main() {
 		log.Fatalf("Error decoding TOML: %v", err)
 	}
 
-	gameState, err := NewGameState(&config, ".")
+	if err := game.CompileConditions(&config); err != nil {
+		log.Fatalf("Error in conditions: %v", err)
+	}
+	for _, err := range game.Validate(&config) {
+		log.Printf("警告: %v", err)
+	}
+
+	gameState, err := NewGameState(&config, filepath.Dir(*bookPath)) // 戦闘結果表などは本と同じ場所に置く
 	if err != nil {
 		log.Fatalf("Error initializing game state: %v", err)
 	}
@@ -0,0 +1,9 @@
+package main
+
+// 使えるルールシステム。import するだけで game.RegisterSystem で登録される
+// 新しいシステムのパッケージはここに加える（詳しくは game/registry.go）
+import (
+	_ "new-gamebook/dnd5e"
+	_ "new-gamebook/fightingfantasy"
+	_ "new-gamebook/lonewolf"
+)